@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ecrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// DefaultMnemonic is the mnemonic of the default prefunded accounts (the same as anvil and hardhat).
+// It is used to derive more accounts if only the number of accounts is set.
+const DefaultMnemonic = "test test test test test test test test test test test junk"
+
+// defaultPrefundedCount is the number of accounts derived from a mnemonic if the count is not set
+const defaultPrefundedCount = 10
+
+// defaultPrefundedBalance is the balance (in wei) of each prefunded account
+var defaultPrefundedBalance, _ = new(big.Int).SetString("10000000000000000000000", 16)
+
+// PrefundedAccount is an account funded in the L1 and L2 genesis
+type PrefundedAccount struct {
+	Address    gethcommon.Address `json:"address"`
+	PrivateKey string             `json:"private_key"`
+	Balance    *hexutil.Big       `json:"balance"`
+
+	// Path is the BIP-44 derivation path if the account is derived from the mnemonic
+	Path string `json:"path,omitempty"`
+}
+
+// AllocEntry is an extra account for the L1 and L2 genesis loaded from an alloc file
+type AllocEntry struct {
+	Address gethcommon.Address                  `json:"address"`
+	Balance string                              `json:"balance"`
+	Code    hexutil.Bytes                       `json:"code,omitempty"`
+	Storage map[gethcommon.Hash]gethcommon.Hash `json:"storage,omitempty"`
+	Nonce   uint64                              `json:"nonce,omitempty"`
+
+	balance *big.Int
+}
+
+// ParseBalance parses a balance in wei either in decimal or in hex (with 0x prefix)
+func ParseBalance(s string) (*big.Int, error) {
+	balance, ok := new(big.Int).SetString(s, 0)
+	if !ok || balance.Sign() < 0 {
+		return nil, fmt.Errorf("invalid balance '%s'", s)
+	}
+	return balance, nil
+}
+
+// LoadAllocFile reads a JSON file with a list of alloc entries
+func LoadAllocFile(path string) ([]*AllocEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alloc file: %w", err)
+	}
+	var entries []*AllocEntry
+	if err := decodeStrict(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode alloc file: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.Balance == "" {
+			entry.balance = new(big.Int)
+			continue
+		}
+		if entry.balance, err = ParseBalance(entry.Balance); err != nil {
+			return nil, fmt.Errorf("alloc entry %s: %w", entry.Address, err)
+		}
+	}
+	return entries, nil
+}
+
+// buildPrefundedAccounts returns the default prefunded accounts plus the ones derived from the mnemonic
+func buildPrefundedAccounts(mnemonic string, count int, balance *big.Int) ([]*PrefundedAccount, error) {
+	prefunded := []*PrefundedAccount{}
+	found := map[gethcommon.Address]bool{}
+
+	for _, privStr := range prefundedAccounts {
+		priv, err := getPrivKey(privStr)
+		if err != nil {
+			return nil, err
+		}
+		account := newPrefundedAccount(priv, balance, "")
+		prefunded = append(prefunded, account)
+		found[account.Address] = true
+	}
+
+	if mnemonic == "" && count == 0 {
+		return prefunded, nil
+	}
+	if mnemonic == "" {
+		mnemonic = DefaultMnemonic
+	}
+	if count == 0 {
+		count = defaultPrefundedCount
+	}
+
+	keys, paths, err := deriveMnemonicKeys(mnemonic, count)
+	if err != nil {
+		return nil, err
+	}
+	for i, priv := range keys {
+		account := newPrefundedAccount(priv, balance, paths[i])
+		if found[account.Address] {
+			// the derived account is already one of the default accounts
+			for _, existing := range prefunded {
+				if existing.Address == account.Address {
+					existing.Path = account.Path
+				}
+			}
+			continue
+		}
+		prefunded = append(prefunded, account)
+		found[account.Address] = true
+	}
+	return prefunded, nil
+}
+
+func newPrefundedAccount(priv *ecdsa.PrivateKey, balance *big.Int, path string) *PrefundedAccount {
+	return &PrefundedAccount{
+		Address:    ecrypto.PubkeyToAddress(priv.PublicKey),
+		PrivateKey: "0x" + hex.EncodeToString(ecrypto.FromECDSA(priv)),
+		Balance:    (*hexutil.Big)(balance),
+		Path:       path,
+	}
+}
+
+// deriveMnemonicKeys derives the first count keys of the BIP-39 mnemonic
+// with the default Ethereum BIP-44 path (m/44'/60'/0'/0/i)
+func deriveMnemonicKeys(mnemonic string, count int) ([]*ecdsa.PrivateKey, []string, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(strings.TrimSpace(mnemonic), "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid mnemonic: %w", err)
+	}
+
+	keys := []*ecdsa.PrivateKey{}
+	paths := []string{}
+	for i := 0; i < count; i++ {
+		path := fmt.Sprintf("m/44'/60'/0'/0/%d", i)
+		derivationPath, err := accounts.ParseDerivationPath(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err := deriveKey(seed, derivationPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to derive key %s: %w", path, err)
+		}
+		keys = append(keys, key)
+		paths = append(paths, path)
+	}
+	return keys, paths, nil
+}
+
+// deriveKey derives the BIP-32 private key for the path from the seed
+func deriveKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key, chainCode := sum[:32], sum[32:]
+	curveN := ecrypto.S256().Params().N
+
+	for _, index := range path {
+		var data []byte
+		if index >= 0x80000000 {
+			// hardened child, use the private key
+			data = append([]byte{0x0}, key...)
+		} else {
+			priv, err := ecrypto.ToECDSA(key)
+			if err != nil {
+				return nil, err
+			}
+			data = ecrypto.CompressPubkey(&priv.PublicKey)
+		}
+		data = binary.BigEndian.AppendUint32(data, index)
+
+		mac := hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		il := new(big.Int).SetBytes(sum[:32])
+		if il.Cmp(curveN) >= 0 {
+			return nil, fmt.Errorf("invalid child key at index %d", index)
+		}
+		child := il.Add(il, new(big.Int).SetBytes(key))
+		child.Mod(child, curveN)
+		if child.Sign() == 0 {
+			return nil, fmt.Errorf("invalid child key at index %d", index)
+		}
+
+		key = child.FillBytes(make([]byte, 32))
+		chainCode = sum[32:]
+	}
+	return ecrypto.ToECDSA(key)
+}
+
+// prefundedAccountsOutput returns the accounts derived from the mnemonic (if any)
+// from the accounts.json artifact to be printed in the recipe output
+func prefundedAccountsOutput(manifest *Manifest) map[string]interface{} {
+	output := map[string]interface{}{}
+
+	data, err := os.ReadFile(filepath.Join(manifest.out.dst, "accounts.json"))
+	if err != nil {
+		return output
+	}
+	var prefunded []*PrefundedAccount
+	if err := json.Unmarshal(data, &prefunded); err != nil {
+		return output
+	}
+
+	lines := []string{}
+	for _, account := range prefunded {
+		if account.Path == "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s %s", account.Path, account.Address, account.PrivateKey))
+	}
+	if len(lines) > 0 {
+		output["prefunded-accounts"] = "\n" + strings.Join(lines, "\n")
+	}
+	return output
+}
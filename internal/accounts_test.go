@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"testing"
+
+	ecrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestDeriveMnemonicKeys(t *testing.T) {
+	// the default prefunded accounts are the first keys of the default mnemonic
+	keys, paths, err := deriveMnemonicKeys(DefaultMnemonic, 10)
+	if err != nil {
+		t.Fatalf("failed to derive keys: %v", err)
+	}
+	for i, key := range keys {
+		expected, err := getPrivKey(prefundedAccounts[i])
+		if err != nil {
+			t.Fatalf("failed to decode key: %v", err)
+		}
+		if ecrypto.PubkeyToAddress(key.PublicKey) != ecrypto.PubkeyToAddress(expected.PublicKey) {
+			t.Fatalf("key %s does not match the prefunded account %d", paths[i], i)
+		}
+	}
+
+	if _, _, err := deriveMnemonicKeys("test test test", 1); err == nil {
+		t.Fatal("expected an error for an invalid mnemonic")
+	}
+}
+
+func TestBuildPrefundedAccounts(t *testing.T) {
+	accounts, err := buildPrefundedAccounts("", 12, defaultPrefundedBalance)
+	if err != nil {
+		t.Fatalf("failed to build accounts: %v", err)
+	}
+
+	// the first 10 derived accounts are already part of the default accounts
+	if len(accounts) != len(prefundedAccounts)+2 {
+		t.Fatalf("expected %d accounts, got %d", len(prefundedAccounts)+2, len(accounts))
+	}
+	if accounts[0].Path != "m/44'/60'/0'/0/0" {
+		t.Fatalf("expected the first default account to be derived, got path '%s'", accounts[0].Path)
+	}
+	if accounts[len(accounts)-1].Path != "m/44'/60'/0'/0/11" {
+		t.Fatalf("unexpected path for the last account '%s'", accounts[len(accounts)-1].Path)
+	}
+}
+
+func TestReservedPrefundedAccounts(t *testing.T) {
+	// the rbuilder coinbase, the op-batcher and the tx spammer do not share accounts
+	coinbase := prefundedAccounts[rbuilderCoinbaseAccount]
+	if coinbase == opRolesPrivateKey {
+		t.Fatal("the rbuilder coinbase is the op-batcher account")
+	}
+	for i, key := range txSpammerAccounts {
+		if key == coinbase || key == opRolesPrivateKey || key == prefundedAccounts[0] {
+			t.Fatalf("the tx spammer account %d is reserved", i)
+		}
+	}
+}
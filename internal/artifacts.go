@@ -15,6 +15,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"text/template"
@@ -26,12 +27,11 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	ecrypto "github.com/ethereum/go-ethereum/crypto"
-	"github.com/hashicorp/go-uuid"
+	gethparams "github.com/ethereum/go-ethereum/params"
 	"github.com/prysmaticlabs/prysm/v5/config/params"
 	"github.com/prysmaticlabs/prysm/v5/crypto/bls/common"
 	"github.com/prysmaticlabs/prysm/v5/runtime/interop"
 	"github.com/prysmaticlabs/prysm/v5/runtime/version"
-	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
 	"gopkg.in/yaml.v2"
 )
 
@@ -46,6 +46,10 @@ var (
 // otherwise, some blocks are missed.
 var MinimumGenesisDelay uint64 = 10
 
+// DeterministicGenesisEpoch (2025-01-01T00:00:00Z) is the base of the genesis time
+// offsets (i.e. '+3600'), used to pin the genesis time of reproducible artifacts.
+var DeterministicGenesisEpoch uint64 = 1735689600
+
 //go:embed utils/rollup.json
 var opRollupConfig []byte
 
@@ -61,12 +65,60 @@ var clConfigContent []byte
 //go:embed utils/query.sh
 var queryReadyCheck []byte
 
+//go:embed rbuilder.toml.tmpl
+var rbuilderConfigContent []byte
+
 type ArtifactsBuilder struct {
 	outputDir         string
 	applyLatestL1Fork bool
 	genesisDelay      uint64
 	applyLatestL2Fork *uint64
 	OpblockTime       uint64
+
+	// genesisTime is a fixed genesis time for the L1 chain. If it is not set,
+	// the genesis time is now plus the genesis delay.
+	genesisTime uint64
+
+	// seed makes the random values of the artifacts (i.e. the keystores) deterministic
+	seed string
+
+	// mnemonic and prefundedCount are used to derive more prefunded accounts
+	mnemonic       string
+	prefundedCount int
+
+	// prefundedBalance is the balance of each prefunded account
+	prefundedBalance *big.Int
+
+	// allocFile is a file with extra accounts for the L1 and L2 genesis
+	allocFile string
+
+	// validatorCount is the number of validators in the beacon chain genesis
+	validatorCount int
+
+	// validatorClients is the number of keystores the validator keys are split into,
+	// one for each validator client
+	validatorClients int
+
+	// clClient is the consensus client that defines the layout of the validator keystores
+	clClient CLClient
+
+	// opDeployer is the op-deployer binary or container image used to deploy the L2 contracts.
+	// If it is not set, the embedded deployment is used. It is also the fallback if op-deployer
+	// fails (i.e. offline) and the embedded deployment matches the requested chains (see
+	// checkEmbeddedDeployment), otherwise the build fails.
+	opDeployer string
+
+	// opProposer is set if the op-proposer runs, it needs the roles of a fresh deployment
+	// so the embedded deployment is not a fallback
+	opProposer bool
+
+	// opParamsFile is a file with the L2 chain params and opParams the values that
+	// override the file (or the defaults)
+	opParamsFile string
+	opParams     *OpChainParams
+
+	// l2Chains is the number of L2 chains that settle on the L1
+	l2Chains int
 }
 
 func NewArtifactsBuilder() *ArtifactsBuilder {
@@ -75,6 +127,10 @@ func NewArtifactsBuilder() *ArtifactsBuilder {
 		applyLatestL1Fork: false,
 		genesisDelay:      MinimumGenesisDelay,
 		OpblockTime:       defaultOpBlockTimeSeconds,
+		validatorCount:    defaultValidatorCount,
+		validatorClients:  1,
+		clClient:          CLClientLighthouse,
+		l2Chains:          1,
 	}
 }
 
@@ -103,8 +159,109 @@ func (b *ArtifactsBuilder) OpBlockTime(blockTimeSeconds uint64) *ArtifactsBuilde
 	return b
 }
 
+func (b *ArtifactsBuilder) GenesisTime(genesisTime uint64) *ArtifactsBuilder {
+	b.genesisTime = genesisTime
+	return b
+}
+
+func (b *ArtifactsBuilder) Seed(seed string) *ArtifactsBuilder {
+	b.seed = seed
+	return b
+}
+
+// PrefundedAccounts derives count accounts from the mnemonic on top of the default
+// prefunded accounts. If only the count is set, the default mnemonic is used.
+func (b *ArtifactsBuilder) PrefundedAccounts(mnemonic string, count int) *ArtifactsBuilder {
+	b.mnemonic = mnemonic
+	b.prefundedCount = count
+	return b
+}
+
+func (b *ArtifactsBuilder) PrefundedBalance(balance *big.Int) *ArtifactsBuilder {
+	b.prefundedBalance = balance
+	return b
+}
+
+func (b *ArtifactsBuilder) AllocFile(path string) *ArtifactsBuilder {
+	b.allocFile = path
+	return b
+}
+
+// ConsensusClient sets the consensus client of the validators. The validator keystores
+// are written in the layout expected by the client.
+func (b *ArtifactsBuilder) ConsensusClient(client CLClient) *ArtifactsBuilder {
+	b.clClient = client
+	return b
+}
+
+// OpDeployer deploys the L2 contracts and generates the L2 genesis and the rollup config
+// with op-deployer instead of using the embedded deployment. The source is either the path
+// to an op-deployer binary or a container image.
+func (b *ArtifactsBuilder) OpDeployer(source string) *ArtifactsBuilder {
+	b.opDeployer = source
+	return b
+}
+
+// OpProposer signals that the op-proposer runs with the deployment
+func (b *ArtifactsBuilder) OpProposer(enabled bool) *ArtifactsBuilder {
+	b.opProposer = enabled
+	return b
+}
+
+// OpChainParams sets the params of the L2 chain from the file (if any) and the params
+// that are set in overrides.
+func (b *ArtifactsBuilder) OpChainParams(file string, overrides *OpChainParams) *ArtifactsBuilder {
+	b.opParamsFile = file
+	b.opParams = overrides
+	return b
+}
+
+// L2Chains sets the number of L2 chains. Each chain has its own genesis and rollup config
+// (see opChainArtifacts) with the chain id of the params plus its index.
+func (b *ArtifactsBuilder) L2Chains(count int) *ArtifactsBuilder {
+	b.l2Chains = count
+	return b
+}
+
+// Validators sets the number of validators in the genesis and the number of
+// validator clients the keys are split into (see validatorKeystoreName).
+func (b *ArtifactsBuilder) Validators(count int, clients int) *ArtifactsBuilder {
+	b.validatorCount = count
+	b.validatorClients = clients
+	return b
+}
+
+// ParseGenesisTime parses a genesis time that is either a unix timestamp or
+// an offset in seconds from the DeterministicGenesisEpoch (i.e. '+3600').
+func ParseGenesisTime(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if offset, ok := strings.CutPrefix(s, "+"); ok {
+		num, err := strconv.ParseUint(offset, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid genesis time offset '%s': %w", s, err)
+		}
+		return DeterministicGenesisEpoch + num, nil
+	}
+	num, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid genesis time '%s': %w", s, err)
+	}
+	return num, nil
+}
+
 type Artifacts struct {
 	Out *output
+
+	// GenesisTime is the genesis time of the L1 chain
+	GenesisTime uint64
+
+	// GenesisForkVersion is the genesis fork version (hex encoded) of the L1 beacon chain
+	GenesisForkVersion string
+
+	// DisputeGameFactory is the L1 address of the dispute game factory of the op chain
+	DisputeGameFactory gethcommon.Address
 }
 
 func (b *ArtifactsBuilder) Build() (*Artifacts, error) {
@@ -132,6 +289,27 @@ func (b *ArtifactsBuilder) Build() (*Artifacts, error) {
 		b.genesisDelay = MinimumGenesisDelay
 	}
 
+	opParams := DefaultOpChainParams()
+	if b.opParamsFile != "" {
+		if opParams, err = LoadOpChainParams(b.opParamsFile); err != nil {
+			return nil, err
+		}
+	}
+	if b.opParams != nil {
+		opParams.Merge(b.opParams)
+	}
+	if err := opParams.Validate(); err != nil {
+		return nil, err
+	}
+	if b.opDeployer == "" {
+		if err := opParams.checkEmbeddedDeployment(1); err != nil {
+			return nil, fmt.Errorf("%v, it requires --op-deployer", err)
+		}
+	}
+	if b.l2Chains <= 0 {
+		return nil, fmt.Errorf("the number of l2 chains must be positive, got %d", b.l2Chains)
+	}
+
 	// enable the latest fork in config.yaml or not
 	var latestForkEpoch string
 	if b.applyLatestL1Fork {
@@ -151,35 +329,94 @@ func (b *ArtifactsBuilder) Build() (*Artifacts, error) {
 	}
 
 	genesisTime := uint64(time.Now().Add(time.Duration(b.genesisDelay) * time.Second).Unix())
+	if b.genesisTime != 0 {
+		genesisTime = b.genesisTime
+		log.Printf("Deterministic genesis, genesis time: %d, seed: '%s'", genesisTime, b.seed)
+		if now := uint64(time.Now().Unix()); genesisTime < now {
+			// the chain can still be inspected (i.e. --dry-run) but the consensus clients
+			// have to go through all the slots since the genesis before producing blocks
+			log.Printf("WARNING: the genesis time %d is %s in the past", genesisTime, time.Duration(now-genesisTime)*time.Second)
+		}
+	} else if b.seed != "" {
+		// the deterministic mode requires a fixed genesis time as well. A pinned default
+		// would be in the past and a default relative to now would not be reproducible.
+		return nil, fmt.Errorf("the seed requires a fixed genesis time (--genesis-time)")
+	}
 	config := params.BeaconConfig()
 
 	gen := interop.GethTestnetGenesis(genesisTime, config)
 	// HACK: fix this in prysm?
 	gen.Config.DepositContractAddress = gethcommon.HexToAddress(config.DepositContractAddress)
+	// geth requires the blob schedule for the cancun and prague forks
+	gen.Config.BlobScheduleConfig = &gethparams.BlobScheduleConfig{
+		Cancun: gethparams.DefaultCancunBlobConfig,
+		Prague: gethparams.DefaultPragueBlobConfig,
+	}
 
 	// add pre-funded accounts
-	prefundedBalance, _ := new(big.Int).SetString("10000000000000000000000", 16)
+	prefundedBalance := b.prefundedBalance
+	if prefundedBalance == nil {
+		prefundedBalance = defaultPrefundedBalance
+	}
+	accounts, err := buildPrefundedAccounts(b.mnemonic, b.prefundedCount, prefundedBalance)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		gen.Alloc[account.Address] = types.Account{
+			Balance: prefundedBalance,
+			Nonce:   1,
+		}
+	}
+
+	var extraAlloc []*AllocEntry
+	if b.allocFile != "" {
+		if extraAlloc, err = LoadAllocFile(b.allocFile); err != nil {
+			return nil, err
+		}
+	}
 
-	for _, privStr := range prefundedAccounts {
-		priv, err := getPrivKey(privStr)
+	// the optimism deployment, either the embedded one or a fresh one from op-deployer
+	opStateData, opGenesisData, opRollupData := opState, [][]byte{opGenesis}, [][]byte{opRollupConfig}
+	if b.opDeployer != "" {
+		workDir, err := out.CreateDir("op-deployer")
 		if err != nil {
 			return nil, err
 		}
-		addr := ecrypto.PubkeyToAddress(priv.PublicKey)
-		gen.Alloc[addr] = types.Account{
-			Balance: prefundedBalance,
-			Nonce:   1,
+		log.Printf("deploying the L2 contracts with op-deployer %s", b.opDeployer)
+		deployment, err := runOpDeployer(b.opDeployer, workDir, gen.Config.ChainID.Uint64(), opParams, b.l2Chains)
+		if err != nil {
+			fallbackErr := opParams.checkEmbeddedDeployment(b.l2Chains)
+			if fallbackErr == nil && b.opProposer {
+				fallbackErr = fmt.Errorf("the op-proposer is not a role of the embedded deployment")
+			}
+			if fallbackErr != nil {
+				return nil, fmt.Errorf("failed to deploy the L2 contracts (%v, no fallback to the embedded deployment): %w", fallbackErr, err)
+			}
+			log.Printf("failed to deploy the L2 contracts, using the embedded deployment: %v", err)
+		} else {
+			opStateData, opGenesisData, opRollupData = deployment.State, deployment.Genesis, deployment.Rollup
 		}
 	}
 
 	// Apply Optimism pre-state
+	var disputeGameFactory gethcommon.Address
+	var deployedChains []*opChainIntent
 	{
-		var state struct {
-			L1StateDump string `json:"l1StateDump"`
-		}
-		if err := json.Unmarshal(opState, &state); err != nil {
+		var state opDeployerState
+		if err := json.Unmarshal(opStateData, &state); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal opState: %w", err)
 		}
+		if len(state.OpChainDeployments) == 0 || len(state.AppliedIntent.Chains) == 0 {
+			return nil, fmt.Errorf("opState does not have any op chain deployment")
+		}
+		disputeGameFactory = state.OpChainDeployments[0].DisputeGameFactoryProxyAddress
+		deployedChains = state.AppliedIntent.Chains
+
+		// each chain has its own L1 contracts, the embedded deployment only has one chain
+		if len(deployedChains) < b.l2Chains || len(opGenesisData) < b.l2Chains {
+			return nil, fmt.Errorf("the op deployment has %d chains, expected %d (more than one chain requires op-deployer)", len(deployedChains), b.l2Chains)
+		}
 
 		decoded, err := base64.StdEncoding.DecodeString(state.L1StateDump)
 		if err != nil {
@@ -206,11 +443,40 @@ func (b *ArtifactsBuilder) Build() (*Artifacts, error) {
 		for addr, account := range alloc {
 			gen.Alloc[addr] = account
 		}
+
+		// fund the proposer, the challenger and the batchers of the extra L2 chains
+		opAccounts := []string{opProposerPrivateKey, opChallengerPrivateKey}
+		for i := 1; i < b.l2Chains; i++ {
+			opAccounts = append(opAccounts, opBatcherPrivateKey(i))
+		}
+		for _, privKey := range opAccounts {
+			addr, err := opAccountAddress(privKey)
+			if err != nil {
+				return nil, err
+			}
+			gen.Alloc[addr] = types.Account{Balance: prefundedBalance}
+		}
+	}
+
+	// Apply the extra allocs last so that they can override any other account
+	for _, entry := range extraAlloc {
+		gen.Alloc[entry.Address] = types.Account{
+			Balance: entry.balance,
+			Code:    entry.Code,
+			Storage: entry.Storage,
+			Nonce:   entry.Nonce,
+		}
 	}
 
 	block := gen.ToBlock()
 	log.Printf("Genesis block hash: %s", block.Hash())
 
+	// the same genesis in the chainspec format for nethermind
+	chainspec, err := nethermindChainspec(gen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chainspec: %w", err)
+	}
+
 	var v int
 	if b.applyLatestL1Fork {
 		v = version.Electra
@@ -218,12 +484,20 @@ func (b *ArtifactsBuilder) Build() (*Artifacts, error) {
 		v = version.Deneb
 	}
 
-	priv, pub, err := interop.DeterministicallyGenerateKeys(0, 100)
+	if b.validatorCount <= 0 {
+		return nil, fmt.Errorf("the number of validators must be positive, got %d", b.validatorCount)
+	}
+	if b.validatorClients <= 0 || b.validatorClients > b.validatorCount {
+		return nil, fmt.Errorf("the number of validator clients must be between 1 and %d, got %d", b.validatorCount, b.validatorClients)
+	}
+	validatorCount := uint64(b.validatorCount)
+
+	priv, pub, err := interop.DeterministicallyGenerateKeys(0, validatorCount)
 	if err != nil {
 		return nil, err
 	}
 
-	depositData, roots, err := interop.DepositDataFromKeysWithExecCreds(priv, pub, 100)
+	depositData, roots, err := interop.DepositDataFromKeysWithExecCreds(priv, pub, validatorCount)
 	if err != nil {
 		return nil, err
 	}
@@ -231,25 +505,33 @@ func (b *ArtifactsBuilder) Build() (*Artifacts, error) {
 	opts := make([]interop.PremineGenesisOpt, 0)
 	opts = append(opts, interop.WithDepositData(depositData, roots))
 
-	state, err := interop.NewPreminedGenesis(context.Background(), genesisTime, 0, 100, v, block, opts...)
+	state, err := interop.NewPreminedGenesis(context.Background(), genesisTime, 0, validatorCount, v, block, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	err = out.WriteBatch(map[string]interface{}{
+	artifacts := map[string]interface{}{
 		"testnet/config.yaml":                 func() ([]byte, error) { return convert(config) },
 		"testnet/genesis.ssz":                 state,
 		"genesis.json":                        gen,
+		"chainspec.json":                      chainspec,
 		"jwtsecret":                           defaultJWTToken,
 		"testnet/boot_enr.yaml":               "[]",
 		"testnet/deploy_block.txt":            "0",
 		"testnet/deposit_contract_block.txt":  "0",
 		"testnet/genesis_validators_root.txt": hex.EncodeToString(state.GenesisValidatorsRoot()),
-		"data_validator/":                     &lighthouseKeystore{privKeys: priv},
 		"deterministic_p2p_key.txt":           defaultDiscoveryPrivKey,
 		"scripts/query.sh":                    queryReadyCheck,
-	})
-	if err != nil {
+		"accounts.json":                       accounts,
+	}
+
+	// split the validator keys in contiguous ranges, one keystore for each validator client
+	for i := 0; i < b.validatorClients; i++ {
+		start, end := i*len(priv)/b.validatorClients, (i+1)*len(priv)/b.validatorClients
+		artifacts[validatorKeystoreName(i, b.validatorClients)+"/"] = newValidatorKeystore(b.clClient, priv[start:end], b.seed)
+	}
+
+	if err := out.WriteBatch(artifacts); err != nil {
 		return nil, err
 	}
 
@@ -271,84 +553,128 @@ func (b *ArtifactsBuilder) Build() (*Artifacts, error) {
 			}
 		}
 
-		// override l2 genesis, make the timestamp start 2 seconds after the L1 genesis
-		input := map[string]interface{}{
-			"timestamp": hexutil.Uint64(opTimestamp).String(),
-		}
-		if forkTime != nil {
-			// We need to enable prague on the EL to enable the engine v4 calls
-			input["config"] = map[string]interface{}{
-				"pragueTime":  *forkTime,
-				"isthmusTime": *forkTime,
-			}
-		}
-
-		// Update the allocs to include the same prefunded accounts as the L1 genesis.
-		allocs := make(map[string]interface{})
-		input["alloc"] = allocs
-		for _, privStr := range prefundedAccounts {
-			priv, err := getPrivKey(privStr)
+		for i := 0; i < b.l2Chains; i++ {
+			chainID := opParams.ChainID + uint64(i)
+			genesisData, rollupData, deployedChain := opGenesisData[i], opRollupData[i], deployedChains[i]
+			batcher, err := opBatcherAddress(i)
 			if err != nil {
 				return nil, err
 			}
-			addr := ecrypto.PubkeyToAddress(priv.PublicKey)
-			allocs[addr.String()] = map[string]interface{}{
-				"balance": "0x10000000000000000000000",
-				"nonce":   "0x1",
+
+			// override l2 genesis, make the timestamp start 2 seconds after the L1 genesis
+			// and apply the chain params
+			opConfig := map[string]interface{}{
+				"chainId": chainID,
+				"optimism": map[string]interface{}{
+					"eip1559Elasticity":        opParams.EIP1559Elasticity,
+					"eip1559Denominator":       opParams.EIP1559Denominator,
+					"eip1559DenominatorCanyon": opParams.EIP1559DenominatorCanyon,
+				},
+			}
+			input := map[string]interface{}{
+				"timestamp": hexutil.Uint64(opTimestamp).String(),
+				"gasLimit":  hexutil.Uint64(opParams.GasLimit).String(),
+				"config":    opConfig,
+			}
+			if forkTime != nil {
+				// We need to enable prague on the EL to enable the engine v4 calls
+				opConfig["pragueTime"] = *forkTime
+				opConfig["isthmusTime"] = *forkTime
 			}
-		}
 
-		newOpGenesis, err := overrideJSON(opGenesis, input)
-		if err != nil {
-			return nil, err
-		}
+			// Update the allocs to include the same prefunded accounts as the L1 genesis.
+			allocs := make(map[string]interface{})
+			input["alloc"] = allocs
+			for _, account := range accounts {
+				allocs[account.Address.String()] = map[string]interface{}{
+					"balance": hexutil.EncodeBig(prefundedBalance),
+					"nonce":   "0x1",
+				}
+			}
+			if err := applyFeeVaultOverrides(genesisData, opParams.feeVaultOverrides(deployedChain), allocs); err != nil {
+				return nil, err
+			}
+			for _, entry := range extraAlloc {
+				alloc := map[string]interface{}{
+					"balance": hexutil.EncodeBig(entry.balance),
+					"nonce":   hexutil.Uint64(entry.Nonce).String(),
+				}
+				if len(entry.Code) != 0 {
+					alloc["code"] = entry.Code.String()
+				}
+				if len(entry.Storage) != 0 {
+					storage := map[string]interface{}{}
+					for k, v := range entry.Storage {
+						storage[k.Hex()] = v.Hex()
+					}
+					alloc["storage"] = storage
+				}
+				allocs[entry.Address.String()] = alloc
+			}
 
-		// the hash of the genesis has changed beause of the timestamp so we need to account for that
-		opGenesisBlock, err := toOpBlock(newOpGenesis)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert opGenesis to block: %w", err)
-		}
+			newOpGenesis, err := overrideJSON(genesisData, input)
+			if err != nil {
+				return nil, err
+			}
 
-		opGenesisHash := opGenesisBlock.Hash()
+			// the hash of the genesis has changed beause of the timestamp so we need to account for that
+			opGenesisBlock, err := toOpBlock(newOpGenesis)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert opGenesis to block: %w", err)
+			}
 
-		// override rollup.json with the real values for the L1 chain and the correct timestamp
-		rollupInput := map[string]interface{}{
-			"genesis": map[string]interface{}{
-				"l2_time": opTimestamp, // this one not in hex
-				"l1": map[string]interface{}{
-					"hash":   block.Hash().String(),
-					"number": 0,
+			opGenesisHash := opGenesisBlock.Hash()
+
+			// override rollup.json with the real values for the L1 chain and the correct timestamp
+			rollupInput := map[string]interface{}{
+				"genesis": map[string]interface{}{
+					"l2_time": opTimestamp, // this one not in hex
+					"l1": map[string]interface{}{
+						"hash":   block.Hash().String(),
+						"number": 0,
+					},
+					"l2": map[string]interface{}{
+						"hash":   opGenesisHash.String(),
+						"number": 0,
+					},
+					"system_config": map[string]interface{}{
+						"gasLimit":    opParams.GasLimit,
+						"batcherAddr": batcher.Hex(),
+					},
 				},
-				"l2": map[string]interface{}{
-					"hash":   opGenesisHash.String(),
-					"number": 0,
+				"block_time":  b.OpblockTime,
+				"l2_chain_id": chainID,
+				"chain_op_config": map[string]interface{}{
+					"eip1559Elasticity":        opParams.EIP1559Elasticity,
+					"eip1559Denominator":       opParams.EIP1559Denominator,
+					"eip1559DenominatorCanyon": opParams.EIP1559DenominatorCanyon,
 				},
-			},
-			"block_time": b.OpblockTime,
-			"chain_op_config": map[string]interface{}{ // TODO: Read this from somewhere (genesis??)
-				"eip1559Elasticity":        6,
-				"eip1559Denominator":       50,
-				"eip1559DenominatorCanyon": 250,
-			},
-		}
-		if forkTime != nil {
-			rollupInput["isthmus_time"] = *forkTime
-		}
+			}
+			if forkTime != nil {
+				rollupInput["isthmus_time"] = *forkTime
+			}
 
-		newOpRollup, err := overrideJSON(opRollupConfig, rollupInput)
-		if err != nil {
-			return nil, err
-		}
+			newOpRollup, err := overrideJSON(rollupData, rollupInput)
+			if err != nil {
+				return nil, err
+			}
 
-		if err := out.WriteFile("l2-genesis.json", newOpGenesis); err != nil {
-			return nil, err
-		}
-		if err := out.WriteFile("rollup.json", newOpRollup); err != nil {
-			return nil, err
+			genesisName, rollupName := opChainArtifacts(i)
+			if err := out.WriteFile(genesisName, newOpGenesis); err != nil {
+				return nil, err
+			}
+			if err := out.WriteFile(rollupName, newOpRollup); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	return &Artifacts{Out: out}, nil
+	return &Artifacts{
+		Out:                out,
+		GenesisTime:        genesisTime,
+		GenesisForkVersion: hexutil.Encode(config.GenesisForkVersion),
+		DisputeGameFactory: disputeGameFactory,
+	}, nil
 }
 
 type OpGenesisTmplInput struct {
@@ -562,29 +888,42 @@ func (o *output) WriteFile(dst string, data interface{}) error {
 
 var secret = "secret"
 
+// defaultValidatorCount is the number of validators in the genesis if it is not set
+const defaultValidatorCount = 100
+
+// validatorKeystoreName returns the name of the artifact with the validator keys of the
+// i-th validator client. If there is only one client, it is 'data_validator' with all the keys.
+func validatorKeystoreName(i int, clients int) string {
+	if clients <= 1 {
+		return "data_validator"
+	}
+	return fmt.Sprintf("data_validator_%d", i)
+}
+
+// newValidatorKeystore returns the keystore with the layout of the consensus client
+func newValidatorKeystore(client CLClient, privKeys []common.SecretKey, seed string) encObject {
+	switch client {
+	case CLClientPrysm:
+		return &prysmKeystore{privKeys: privKeys, seed: seed}
+	case CLClientTeku:
+		return &tekuKeystore{privKeys: privKeys, seed: seed}
+	default:
+		return &lighthouseKeystore{privKeys: privKeys, seed: seed}
+	}
+}
+
 type lighthouseKeystore struct {
 	privKeys []common.SecretKey
+
+	// seed is used to derive the salt, the iv and the uuid of the keystores.
+	// If it is empty, they are random.
+	seed string
 }
 
 func (l *lighthouseKeystore) Encode(o *output) error {
 	for _, key := range l.privKeys {
-		encryptor := keystorev4.New()
-		cryptoFields, err := encryptor.Encrypt(key.Marshal(), secret)
-		if err != nil {
-			return err
-		}
-
-		id, _ := uuid.GenerateUUID()
-
 		pubKeyHex := "0x" + hex.EncodeToString(key.PublicKey().Marshal())
-		item := map[string]interface{}{
-			"crypto":      cryptoFields,
-			"uuid":        id,
-			"pubkey":      pubKeyHex[2:], // without 0x in the json file
-			"version":     4,
-			"description": "",
-		}
-		valJSON, err := json.MarshalIndent(item, "", "\t")
+		valJSON, err := encodeValidatorKeystore(key, l.seed)
 		if err != nil {
 			return err
 		}
@@ -686,6 +1025,21 @@ var prefundedAccounts = []string{
 	"0xac431098061ca49f5b36121d01a17d30e1d0624227d08b583ff328f1efe0d4a2",
 }
 
+// rbuilderRelaySecretKey is the BLS key used by rbuilder to sign the blocks submitted to the relay.
+// It is sha256("rbuilder") modulo the BLS12-381 curve order and it must not be the key of the
+// relay (mevboostrelay.DefaultSecretKey), otherwise the builder signs with the relay identity.
+const rbuilderRelaySecretKey = "5dfc6717c9da6098b355dc4e65183cba858ecd3fb72304e8c5f5257d82119cef"
+
+// rbuilderCoinbaseAccount is the index of the prefunded account used as the rbuilder coinbase.
+// It pays the proposer at the end of each block, so it is not the first account to avoid
+// nonce conflicts with the transactions sent by the users. It is not the last account either,
+// the account 9 is the roles key of the op chains (opRolesPrivateKey) used by the op-batcher.
+const rbuilderCoinbaseAccount = 8
+
+// txSpammerAccounts are the prefunded accounts used by the tx spammer. The first account is
+// left for the users and the last ones for the rbuilder coinbase and the op-batcher.
+var txSpammerAccounts = prefundedAccounts[1:rbuilderCoinbaseAccount]
+
 func applyTemplate2(templateStr []byte, input interface{}) ([]byte, error) {
 	tpl, err := template.New("").Parse(string(templateStr))
 	if err != nil {
@@ -0,0 +1,471 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/prysmaticlabs/prysm/v5/runtime/interop"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+)
+
+// hashArtifacts returns the sha256 hash of every file in the output folder
+func hashArtifacts(t *testing.T, dir string) map[string]string {
+	hashes := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(content)
+		hashes[rel] = hex.EncodeToString(hash[:])
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to hash artifacts: %v", err)
+	}
+	return hashes
+}
+
+func buildDeterministicArtifacts(t *testing.T, seed string) string {
+	dir := t.TempDir()
+	_, err := NewArtifactsBuilder().
+		OutputDir(dir).
+		GenesisTime(DeterministicGenesisEpoch).
+		Seed(seed).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build artifacts: %v", err)
+	}
+	return dir
+}
+
+func TestArtifactsSeedRequiresGenesisTime(t *testing.T) {
+	_, err := NewArtifactsBuilder().OutputDir(t.TempDir()).Seed("test").Build()
+	if err == nil || !strings.Contains(err.Error(), "--genesis-time") {
+		t.Fatalf("expected the seed to require a genesis time, got %v", err)
+	}
+}
+
+func TestArtifactsDeterministic(t *testing.T) {
+	dir1 := buildDeterministicArtifacts(t, "test")
+	dir2 := buildDeterministicArtifacts(t, "test")
+
+	hashes1 := hashArtifacts(t, dir1)
+	hashes2 := hashArtifacts(t, dir2)
+
+	for _, name := range []string{"genesis.json", "testnet/genesis.ssz", "l2-genesis.json", "rollup.json"} {
+		if _, ok := hashes1[name]; !ok {
+			t.Fatalf("artifact %s not found", name)
+		}
+	}
+	if len(hashes1) != len(hashes2) {
+		t.Fatalf("expected %d artifacts, got %d", len(hashes1), len(hashes2))
+	}
+	for name, hash := range hashes1 {
+		if hashes2[name] != hash {
+			t.Fatalf("artifact %s is not deterministic: %s != %s", name, hash, hashes2[name])
+		}
+	}
+
+	// the L1 genesis block is pinned by the genesis time
+	var rollup struct {
+		Genesis struct {
+			L1 struct {
+				Hash string `json:"hash"`
+			} `json:"l1"`
+		} `json:"genesis"`
+	}
+	data, err := os.ReadFile(filepath.Join(dir1, "rollup.json"))
+	if err != nil {
+		t.Fatalf("failed to read rollup.json: %v", err)
+	}
+	if err := json.Unmarshal(data, &rollup); err != nil {
+		t.Fatalf("failed to decode rollup.json: %v", err)
+	}
+	expectedL1Hash := "0x773e38b7ca77d569c8e226e51521817ecb442e02439b1497e11a2974373d9388"
+	if rollup.Genesis.L1.Hash != expectedL1Hash {
+		t.Fatalf("expected L1 genesis hash %s, got %s", expectedL1Hash, rollup.Genesis.L1.Hash)
+	}
+
+	// the keystores change with the seed but the genesis does not
+	hashes3 := hashArtifacts(t, buildDeterministicArtifacts(t, "other"))
+	if hashes3["genesis.json"] != hashes1["genesis.json"] {
+		t.Fatal("expected the same genesis for a different seed")
+	}
+
+	priv, pub, err := interop.DeterministicallyGenerateKeys(0, 1)
+	if err != nil {
+		t.Fatalf("failed to generate keys: %v", err)
+	}
+	keystorePath := filepath.Join("data_validator", "validators", "0x"+hex.EncodeToString(pub[0].Marshal()), "voting-keystore.json")
+	if hashes3[keystorePath] == hashes1[keystorePath] {
+		t.Fatal("expected a different keystore for a different seed")
+	}
+
+	// the deterministic keystore can be decrypted as a regular keystore
+	data, err = os.ReadFile(filepath.Join(dir1, keystorePath))
+	if err != nil {
+		t.Fatalf("failed to read keystore: %v", err)
+	}
+	var keystore struct {
+		Crypto map[string]interface{} `json:"crypto"`
+	}
+	if err := json.Unmarshal(data, &keystore); err != nil {
+		t.Fatalf("failed to decode keystore: %v", err)
+	}
+	decrypted, err := keystorev4.New().Decrypt(keystore.Crypto, secret)
+	if err != nil {
+		t.Fatalf("failed to decrypt keystore: %v", err)
+	}
+	if hex.EncodeToString(decrypted) != hex.EncodeToString(priv[0].Marshal()) {
+		t.Fatal("decrypted key does not match the validator key")
+	}
+}
+
+func TestArtifactsValidatorClients(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewArtifactsBuilder().
+		OutputDir(dir).
+		Validators(5, 2).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build artifacts: %v", err)
+	}
+
+	// the keys are split in contiguous ranges, the last client gets the remainder
+	for i, expected := range []int{2, 3} {
+		entries, err := os.ReadDir(filepath.Join(dir, validatorKeystoreName(i, 2), "validators"))
+		if err != nil {
+			t.Fatalf("failed to read keystore %d: %v", i, err)
+		}
+		if len(entries) != expected {
+			t.Fatalf("expected %d keys in keystore %d, got %d", expected, i, len(entries))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "data_validator")); !os.IsNotExist(err) {
+		t.Fatal("expected no 'data_validator' keystore with multiple clients")
+	}
+
+	if _, err := NewArtifactsBuilder().OutputDir(t.TempDir()).Validators(2, 3).Build(); err == nil {
+		t.Fatal("expected an error with more validator clients than validators")
+	}
+}
+
+func TestArtifactsOpChainParams(t *testing.T) {
+	dir := t.TempDir()
+	paramsFile := filepath.Join(dir, "params.json")
+	if err := os.WriteFile(paramsFile, []byte(`{"eip1559_elasticity": 4, "eip1559_denominator": 40}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the flags take precedence over the file
+	out := filepath.Join(dir, "out")
+	_, err := NewArtifactsBuilder().
+		OutputDir(out).
+		OpChainParams(paramsFile, &OpChainParams{EIP1559Denominator: 60}).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build artifacts: %v", err)
+	}
+
+	type eip1559Params struct {
+		EIP1559Elasticity  uint64 `json:"eip1559Elasticity"`
+		EIP1559Denominator uint64 `json:"eip1559Denominator"`
+	}
+	var genesis struct {
+		Config struct {
+			ChainID  uint64        `json:"chainId"`
+			Optimism eip1559Params `json:"optimism"`
+		} `json:"config"`
+	}
+	genesisData, err := os.ReadFile(filepath.Join(out, "l2-genesis.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(genesisData, &genesis); err != nil {
+		t.Fatal(err)
+	}
+	if genesis.Config.ChainID != defaultOpL2ChainID || genesis.Config.Optimism != (eip1559Params{4, 60}) {
+		t.Fatalf("unexpected l2 genesis params: %+v", genesis)
+	}
+
+	var rollup struct {
+		Genesis struct {
+			L2 struct {
+				Hash string `json:"hash"`
+			} `json:"l2"`
+		} `json:"genesis"`
+		ChainOpConfig eip1559Params `json:"chain_op_config"`
+	}
+	rollupData, err := os.ReadFile(filepath.Join(out, "rollup.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(rollupData, &rollup); err != nil {
+		t.Fatal(err)
+	}
+	if rollup.ChainOpConfig != (eip1559Params{4, 60}) {
+		t.Fatalf("unexpected rollup params: %+v", rollup)
+	}
+
+	// the rollup config points to the genesis with the new params
+	block, err := toOpBlock(genesisData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Hash().String() != rollup.Genesis.L2.Hash {
+		t.Fatalf("expected l2 genesis hash %s, got %s", block.Hash(), rollup.Genesis.L2.Hash)
+	}
+
+	// the chain id and the gas limit are part of the L1 contracts of the embedded deployment
+	if err := os.WriteFile(paramsFile, []byte(`{"chain_id": 4242}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, builder := range []*ArtifactsBuilder{
+		NewArtifactsBuilder().OpChainParams(paramsFile, nil),
+		NewArtifactsBuilder().OpChainParams("", &OpChainParams{GasLimit: 90_000_000}),
+	} {
+		if _, err := builder.OutputDir(t.TempDir()).Build(); err == nil || !strings.Contains(err.Error(), "--op-deployer") {
+			t.Fatalf("expected the embedded deployment to reject the params, got %v", err)
+		}
+	}
+}
+
+func TestApplyFeeVaultOverrides(t *testing.T) {
+	oldRecipient := gethcommon.HexToAddress("0xaff0ca253b97e54440965855cec0a8a2e2399896")
+	newRecipient := gethcommon.HexToAddress("0x0000000000000000000000000000000000000001")
+
+	// PUSH32 <recipient> as the immutable in the code of the implementation
+	code := append([]byte{0x7f}, gethcommon.LeftPadBytes(oldRecipient.Bytes(), 32)...)
+	genesis := fmt.Sprintf(`{"alloc": {"c0d3c0d3c0d3c0d3c0d3c0d3c0d3c0d3c0d30019": {"code": "%s", "balance": "0x0"}}}`, hexutil.Encode(code))
+
+	params := &OpChainParams{BaseFeeVaultRecipient: newRecipient}
+	overrides := params.feeVaultOverrides(&opChainIntent{BaseFeeVaultRecipient: oldRecipient})
+	if len(overrides) != 1 {
+		t.Fatalf("expected one fee vault override, got %d", len(overrides))
+	}
+
+	allocs := map[string]interface{}{}
+	if err := applyFeeVaultOverrides([]byte(genesis), overrides, allocs); err != nil {
+		t.Fatal(err)
+	}
+	expected := hexutil.Encode(append([]byte{0x7f}, gethcommon.LeftPadBytes(newRecipient.Bytes(), 32)...))
+	account, ok := allocs["c0d3c0d3c0d3c0d3c0d3c0d3c0d3c0d3c0d30019"].(map[string]interface{})
+	if !ok || account["code"] != expected {
+		t.Fatalf("unexpected fee vault override: %v", allocs)
+	}
+
+	// the rest of the vaults are not in the genesis
+	params = &OpChainParams{L1FeeVaultRecipient: newRecipient}
+	if err := applyFeeVaultOverrides([]byte(genesis), params.feeVaultOverrides(&opChainIntent{L1FeeVaultRecipient: oldRecipient}), allocs); err == nil {
+		t.Fatal("expected an error for a fee vault that is not in the genesis")
+	}
+}
+
+func TestValidatorKeystoreLayouts(t *testing.T) {
+	priv, pub, err := interop.DeterministicallyGenerateKeys(0, 2)
+	if err != nil {
+		t.Fatalf("failed to generate keys: %v", err)
+	}
+
+	// teku has a keystore and a password file with the same name for each key
+	dir := t.TempDir()
+	if err := newValidatorKeystore(CLClientTeku, priv, "test").Encode(&output{dst: dir}); err != nil {
+		t.Fatalf("failed to encode teku keystore: %v", err)
+	}
+	for _, key := range pub {
+		name := "0x" + hex.EncodeToString(key.Marshal())
+		for _, path := range []string{filepath.Join("keys", name+".json"), filepath.Join("secrets", name+".txt")} {
+			if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+				t.Fatalf("teku file %s not found: %v", path, err)
+			}
+		}
+	}
+
+	// prysm has a single wallet keystore with all the keys
+	dir = t.TempDir()
+	if err := newValidatorKeystore(CLClientPrysm, priv, "test").Encode(&output{dst: dir}); err != nil {
+		t.Fatalf("failed to encode prysm keystore: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "wallet", "direct", "accounts", "all-accounts.keystore.json"))
+	if err != nil {
+		t.Fatalf("failed to read prysm wallet: %v", err)
+	}
+	var keystore struct {
+		Crypto map[string]interface{} `json:"crypto"`
+	}
+	if err := json.Unmarshal(data, &keystore); err != nil {
+		t.Fatalf("failed to decode prysm wallet: %v", err)
+	}
+	decrypted, err := keystorev4.New().Decrypt(keystore.Crypto, secret)
+	if err != nil {
+		t.Fatalf("failed to decrypt prysm wallet: %v", err)
+	}
+	var store struct {
+		PrivateKeys [][]byte `json:"private_keys"`
+		PublicKeys  [][]byte `json:"public_keys"`
+	}
+	if err := json.Unmarshal(decrypted, &store); err != nil {
+		t.Fatalf("failed to decode prysm accounts: %v", err)
+	}
+	if len(store.PublicKeys) != len(pub) {
+		t.Fatalf("expected %d accounts, got %d", len(pub), len(store.PublicKeys))
+	}
+	for i, key := range pub {
+		if hex.EncodeToString(store.PublicKeys[i]) != hex.EncodeToString(key.Marshal()) {
+			t.Fatalf("unexpected public key for account %d", i)
+		}
+	}
+}
+
+// fakeOpDeployer writes an op-deployer script that returns the embedded deployment for all the
+// chains with the given batch inboxes (one per chain id)
+func fakeOpDeployer(t *testing.T, chainID uint64, inboxes []gethcommon.Address) string {
+	dir := t.TempDir()
+
+	var state map[string]interface{}
+	if err := json.Unmarshal(opState, &state); err != nil {
+		t.Fatal(err)
+	}
+	deployments := state["opChainDeployments"].([]interface{})
+	intent := state["appliedIntent"].(map[string]interface{})
+	chains := intent["chains"].([]interface{})
+	for i, inbox := range inboxes {
+		if i > 0 {
+			deployments = append(deployments, deployments[0])
+			chains = append(chains, chains[0])
+		}
+		var rollup map[string]interface{}
+		if err := json.Unmarshal(opRollupConfig, &rollup); err != nil {
+			t.Fatal(err)
+		}
+		rollup["batch_inbox_address"] = inbox.Hex()
+		data, err := json.Marshal(rollup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("rollup-%d.json", chainID+uint64(i))), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state["opChainDeployments"], intent["chains"] = deployments, chains
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "state.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "genesis.json"), opGenesis, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// inspect <genesis|rollup> --workdir . --outfile <file> <chain id>
+	script := fmt.Sprintf(`#!/bin/sh
+case "$1 $2" in
+  "apply "*) cp %[1]s/state.json state.json ;;
+  "inspect genesis") cp %[1]s/genesis.json $6 ;;
+  "inspect rollup") cp %[1]s/rollup-$7.json $6 ;;
+esac
+`, dir)
+	path := filepath.Join(dir, "op-deployer")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestArtifactsL2Chains(t *testing.T) {
+	// the extra chains require the contracts deployed by op-deployer
+	if _, err := NewArtifactsBuilder().OutputDir(t.TempDir()).L2Chains(2).Build(); err == nil {
+		t.Fatal("expected an error for two chains with the embedded deployment")
+	}
+
+	params := DefaultOpChainParams()
+	inboxes := []gethcommon.Address{
+		gethcommon.HexToAddress("0x00a0000000000000000000000000000000000000"),
+		gethcommon.HexToAddress("0x00a1000000000000000000000000000000000000"),
+	}
+	out := t.TempDir()
+	_, err := NewArtifactsBuilder().
+		OutputDir(out).
+		OpDeployer(fakeOpDeployer(t, params.ChainID, inboxes)).
+		L2Chains(2).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build artifacts: %v", err)
+	}
+
+	for i, inbox := range inboxes {
+		genesisName, rollupName := opChainArtifacts(i)
+		genesisData, err := os.ReadFile(filepath.Join(out, genesisName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var genesis struct {
+			Config struct {
+				ChainID uint64 `json:"chainId"`
+			} `json:"config"`
+		}
+		if err := json.Unmarshal(genesisData, &genesis); err != nil {
+			t.Fatal(err)
+		}
+
+		var rollup struct {
+			Genesis struct {
+				L2 struct {
+					Hash gethcommon.Hash `json:"hash"`
+				} `json:"l2"`
+				SystemConfig struct {
+					BatcherAddr gethcommon.Address `json:"batcherAddr"`
+				} `json:"system_config"`
+			} `json:"genesis"`
+			L2ChainID         uint64             `json:"l2_chain_id"`
+			BatchInboxAddress gethcommon.Address `json:"batch_inbox_address"`
+		}
+		rollupData, err := os.ReadFile(filepath.Join(out, rollupName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(rollupData, &rollup); err != nil {
+			t.Fatal(err)
+		}
+
+		chainID := params.ChainID + uint64(i)
+		if genesis.Config.ChainID != chainID || rollup.L2ChainID != chainID {
+			t.Fatalf("expected chain id %d for chain %d, got %d and %d", chainID, i, genesis.Config.ChainID, rollup.L2ChainID)
+		}
+		block, err := toOpBlock(genesisData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rollup.Genesis.L2.Hash != block.Hash() {
+			t.Fatalf("expected l2 genesis hash %s for chain %d, got %s", block.Hash(), i, rollup.Genesis.L2.Hash)
+		}
+		if rollup.BatchInboxAddress != inbox {
+			t.Fatalf("expected the batch inbox %s of the deployment for chain %d, got %s", inbox, i, rollup.BatchInboxAddress)
+		}
+		batcher, err := opBatcherAddress(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rollup.Genesis.SystemConfig.BatcherAddr != batcher {
+			t.Fatalf("expected batcher %s for chain %d, got %s", batcher, i, rollup.Genesis.SystemConfig.BatcherAddr)
+		}
+	}
+}
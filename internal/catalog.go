@@ -8,15 +8,30 @@ func register(component ServiceGen) {
 
 func init() {
 	register(&OpBatcher{})
+	register(&OpProposer{})
+	register(&OpChallenger{})
 	register(&OpGeth{})
 	register(&OpNode{})
 	register(&RethEL{})
+	register(&GethEL{})
+	register(&NethermindEL{})
 	register(&LighthouseBeaconNode{})
 	register(&LighthouseValidator{})
+	register(&PrysmBeaconNode{})
+	register(&PrysmValidator{})
+	register(&TekuBeaconNode{})
+	register(&TekuValidator{})
 	register(&ClProxy{})
 	register(&MevBoostRelay{})
+	register(&MevBoost{})
+	register(&Rbuilder{})
+	register(&TxSpammer{})
+	register(&Blockscout{})
+	register(&BlockscoutPostgres{})
 	register(&RollupBoost{})
 	register(&OpReth{})
+	register(&OpRbuilder{})
+	register(&FlashblocksProxy{})
 	register(&BuilderHub{})
 	register(&BuilderHubPostgres{})
 	register(&BuilderHubMockProxy{})
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"fmt"
+	"math/big"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	gethparams "github.com/ethereum/go-ethereum/params"
+)
+
+// nethermindChainspec converts the L1 genesis into the chainspec format used by Nethermind.
+// It only supports post-merge chains where all the block based forks are active at genesis.
+func nethermindChainspec(gen *core.Genesis) (map[string]interface{}, error) {
+	cfg := gen.Config
+	if cfg.ShanghaiTime == nil || cfg.CancunTime == nil {
+		return nil, fmt.Errorf("the genesis must enable shanghai and cancun")
+	}
+
+	zero := hexutil.Uint64(0)
+	chainParams := map[string]interface{}{
+		"chainID":                 hexutil.EncodeBig(cfg.ChainID),
+		"networkID":               hexutil.EncodeBig(cfg.ChainID),
+		"gasLimitBoundDivisor":    "0x400",
+		"maximumExtraDataSize":    "0x20",
+		"minGasLimit":             "0x1388",
+		"maxCodeSize":             "0x6000",
+		"maxCodeSizeTransition":   zero,
+		"terminalTotalDifficulty": "0x0",
+		"depositContractAddress":  cfg.DepositContractAddress,
+	}
+
+	// block based forks, all of them are active at genesis
+	for _, eip := range []string{
+		"eip150", "eip155", "eip158", "eip160", "eip161abc", "eip161d", "eip140", "eip211", "eip214",
+		"eip658", "eip145", "eip1014", "eip1052", "eip1283", "eip1283Disable", "eip152", "eip1108",
+		"eip1344", "eip1884", "eip2028", "eip2200", "eip2565", "eip2929", "eip2930", "eip1559",
+		"eip3198", "eip3529", "eip3541",
+	} {
+		chainParams[eip+"Transition"] = zero
+	}
+
+	// time based forks
+	forks := []struct {
+		time *uint64
+		eips []string
+	}{
+		{cfg.ShanghaiTime, []string{"eip3651", "eip3855", "eip3860", "eip4895"}},
+		{cfg.CancunTime, []string{"eip4844", "eip4788", "eip1153", "eip5656", "eip6780"}},
+		{cfg.PragueTime, []string{"eip2537", "eip2935", "eip6110", "eip7002", "eip7251", "eip7623", "eip7702"}},
+	}
+	for _, fork := range forks {
+		if fork.time == nil {
+			continue
+		}
+		for _, eip := range fork.eips {
+			chainParams[eip+"TransitionTimestamp"] = hexutil.Uint64(*fork.time)
+		}
+	}
+
+	if schedule := cfg.BlobScheduleConfig; schedule != nil {
+		blobSchedule := []map[string]interface{}{}
+		for _, entry := range []struct {
+			time   *uint64
+			config *gethparams.BlobConfig
+		}{
+			{cfg.CancunTime, schedule.Cancun},
+			{cfg.PragueTime, schedule.Prague},
+		} {
+			if entry.time == nil || entry.config == nil {
+				continue
+			}
+			blobSchedule = append(blobSchedule, map[string]interface{}{
+				"timestamp":             hexutil.Uint64(*entry.time),
+				"target":                entry.config.Target,
+				"max":                   entry.config.Max,
+				"baseFeeUpdateFraction": entry.config.UpdateFraction,
+			})
+		}
+		chainParams["blobSchedule"] = blobSchedule
+	}
+
+	baseFee := gen.BaseFee
+	if baseFee == nil {
+		baseFee = big.NewInt(gethparams.InitialBaseFee)
+	}
+
+	genesis := map[string]interface{}{
+		"seal": map[string]interface{}{
+			"ethereum": map[string]interface{}{
+				"nonce":   hexutil.EncodeUint64(gen.Nonce),
+				"mixHash": gen.Mixhash,
+			},
+		},
+		"difficulty":    hexutil.EncodeBig(gen.Difficulty),
+		"author":        gen.Coinbase,
+		"timestamp":     hexutil.Uint64(gen.Timestamp),
+		"parentHash":    gen.ParentHash,
+		"extraData":     hexutil.Bytes(gen.ExtraData),
+		"gasLimit":      hexutil.Uint64(gen.GasLimit),
+		"baseFeePerGas": hexutil.EncodeBig(baseFee),
+	}
+	if cfg.CancunTime != nil && *cfg.CancunTime <= gen.Timestamp {
+		genesis["blobGasUsed"] = "0x0"
+		genesis["excessBlobGas"] = "0x0"
+		genesis["parentBeaconBlockRoot"] = gethcommon.Hash{}
+	}
+
+	accounts := map[string]interface{}{}
+	for addr, account := range gen.Alloc {
+		balance := account.Balance
+		if balance == nil {
+			balance = new(big.Int)
+		}
+		entry := map[string]interface{}{
+			"balance": hexutil.EncodeBig(balance),
+		}
+		if account.Nonce != 0 {
+			entry["nonce"] = hexutil.Uint64(account.Nonce)
+		}
+		if len(account.Code) != 0 {
+			entry["code"] = hexutil.Bytes(account.Code)
+		}
+		if len(account.Storage) != 0 {
+			entry["storage"] = account.Storage
+		}
+		accounts[addr.Hex()] = entry
+	}
+
+	return map[string]interface{}{
+		"name":     "playground",
+		"engine":   map[string]interface{}{"Ethash": map[string]interface{}{}},
+		"params":   chainParams,
+		"genesis":  genesis,
+		"accounts": accounts,
+	}, nil
+}
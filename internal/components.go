@@ -2,11 +2,17 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"io"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ecrypto "github.com/ethereum/go-ethereum/crypto"
 )
 
 var defaultJWTToken = "04592280e1778419b7aa954d43871cb2cfb2ebda754fb735e8adeb293a88f9bf"
@@ -14,12 +20,21 @@ var defaultJWTToken = "04592280e1778419b7aa954d43871cb2cfb2ebda754fb735e8adeb293
 type RollupBoost struct {
 	ELNode  string
 	Builder string
+
+	// FlashblocksBuilder is the flashblocks websocket of the builder. If it is set, rollup-boost
+	// relays the flashblocks of the builder in its own 'flashblocks' websocket.
+	FlashblocksBuilder string
 }
 
 func (r *RollupBoost) Run(service *Service, ctx *ExContext) {
+	// flashblocks are only supported since v0.5
+	tag := "0.4rc1"
+	if r.FlashblocksBuilder != "" {
+		tag = "v0.7.0"
+	}
 	service.
 		WithImage("docker.io/flashbots/rollup-boost").
-		WithTag("0.4rc1").
+		WithTag(tag).
 		WithArgs(
 			"--rpc-port", `{{Port "authrpc" 8551}}`,
 			"--l2-jwt-path", "/data/jwtsecret",
@@ -27,6 +42,14 @@ func (r *RollupBoost) Run(service *Service, ctx *ExContext) {
 			"--builder-jwt-path", "/data/jwtsecret",
 			"--builder-url", r.Builder,
 		).WithArtifact("/data/jwtsecret", "jwtsecret")
+	if r.FlashblocksBuilder != "" {
+		service.WithArgs(
+			"--flashblocks",
+			"--flashblocks-builder-url", r.FlashblocksBuilder,
+			"--flashblocks-host", "0.0.0.0",
+			"--flashblocks-port", `{{Port "flashblocks" 1112}}`,
+		)
+	}
 	if ctx.AlloyEnabled {
 		service.
 			WithArgs(
@@ -45,12 +68,18 @@ type OpBatcher struct {
 	L2Node             string
 	RollupNode         string
 	MaxChannelDuration uint64
+
+	// PrivateKey is the key of the batcher account, it defaults to the roles key
+	PrivateKey string
 }
 
 func (o *OpBatcher) Run(service *Service, ctx *ExContext) {
 	if o.MaxChannelDuration == 0 {
 		o.MaxChannelDuration = 2
 	}
+	if o.PrivateKey == "" {
+		o.PrivateKey = opRolesPrivateKey
+	}
 	service.
 		WithImage("us-docker.pkg.dev/oplabs-tools-artifacts/images/op-batcher").
 		WithTag("v1.12.0-rc.1").
@@ -63,7 +92,7 @@ func (o *OpBatcher) Run(service *Service, ctx *ExContext) {
 			"--sub-safety-margin=4",
 			"--poll-interval=1s",
 			"--num-confirmations=1",
-			"--private-key=0x2a871d0798f97d79848a013d4936a73bf4cc922c825d33c1cf7073dff6d409c6",
+			"--private-key="+o.PrivateKey,
 		)
 }
 
@@ -71,13 +100,184 @@ func (o *OpBatcher) Name() string {
 	return "op-batcher"
 }
 
+// l1BlockTime is the block time of the L1 (SECONDS_PER_SLOT in config.yaml.tmpl)
+const l1BlockTime = 12 * time.Second
+
+var _ ServiceWatchdog = &OpBatcher{}
+
+func (o *OpBatcher) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
+	// the batcher and the batch inbox are the ones of the rollup config used by the op-node
+	opNode, ok := instance.manifest.MustGetService(o.RollupNode).component.(*OpNode)
+	if !ok {
+		return fmt.Errorf("rollup node %s is not an op-node", o.RollupNode)
+	}
+	data, err := os.ReadFile(filepath.Join(instance.manifest.out.dst, opNode.RollupConfig))
+	if err != nil {
+		return fmt.Errorf("failed to read the rollup config: %w", err)
+	}
+	var rollup struct {
+		BatchInboxAddress gethcommon.Address `json:"batch_inbox_address"`
+		Genesis           struct {
+			SystemConfig struct {
+				BatcherAddr gethcommon.Address `json:"batcherAddr"`
+			} `json:"system_config"`
+		} `json:"genesis"`
+	}
+	if err := json.Unmarshal(data, &rollup); err != nil {
+		return fmt.Errorf("failed to decode the rollup config: %w", err)
+	}
+
+	l1URL := fmt.Sprintf("http://localhost:%d", instance.manifest.MustGetService(o.L1Node).MustGetPort("http").HostPort)
+	window := time.Duration(o.MaxChannelDuration)*l1BlockTime + out.Duration("batches", "slack")
+	return watchBatches(out, l1URL, rollup.Genesis.SystemConfig.BatcherAddr, rollup.BatchInboxAddress, window)
+}
+
+// opRolesPrivateKey is the private key of the owner roles and of the batcher of the op chain
+const opRolesPrivateKey = "0x2a871d0798f97d79848a013d4936a73bf4cc922c825d33c1cf7073dff6d409c6"
+
+// opProposerPrivateKey and opChallengerPrivateKey are the keys of the proposer and challenger
+// roles, keccak256("op-proposer") and keccak256("op-challenger"). They do not share the account
+// of the batcher so that the services do not race for the same nonces.
+const (
+	opProposerPrivateKey   = "0x27f1b490eef664adb28943f0d9a780f136faf52d13461c03378ae5c511f83f8f"
+	opChallengerPrivateKey = "0x031aa3a4691be250728ec9fe8895eb3a727564192d6893c69d846137b4e74fd3"
+)
+
+// opBatcherPrivateKey returns the key of the op-batcher of the i-th L2 chain. The batchers of
+// the chains that share the L1 cannot use the same account, so the first chain uses the roles
+// key and the other ones the key keccak256("op-batcher-<i>").
+func opBatcherPrivateKey(i int) string {
+	if i == 0 {
+		return opRolesPrivateKey
+	}
+	return hexutil.Encode(ecrypto.Keccak256([]byte(fmt.Sprintf("op-batcher-%d", i))))
+}
+
+// opBatcherAddress returns the address of the op-batcher of the i-th L2 chain
+func opBatcherAddress(i int) (gethcommon.Address, error) {
+	return opAccountAddress(opBatcherPrivateKey(i))
+}
+
+// opAccountAddress returns the address of the account of an op role key
+func opAccountAddress(privKey string) (gethcommon.Address, error) {
+	priv, err := getPrivKey(privKey)
+	if err != nil {
+		return gethcommon.Address{}, err
+	}
+	return ecrypto.PubkeyToAddress(priv.PublicKey), nil
+}
+
+// opPermissionedGameType is the type of the permissioned dispute game (the only one in state.json)
+const opPermissionedGameType = "1"
+
+// OpProposer posts the L2 output roots to L1 as dispute games of the dispute game factory
+type OpProposer struct {
+	L1Node     string
+	RollupNode string
+
+	// DisputeGameFactory is the L1 address of the dispute game factory
+	DisputeGameFactory string
+
+	// ProposalInterval is the interval between the output roots posted to L1
+	ProposalInterval time.Duration
+}
+
+func (o *OpProposer) Run(service *Service, ctx *ExContext) {
+	if o.ProposalInterval == 0 {
+		o.ProposalInterval = 12 * time.Second
+	}
+	service.
+		WithImage("us-docker.pkg.dev/oplabs-tools-artifacts/images/op-proposer").
+		WithTag("v1.10.0").
+		WithEntrypoint("op-proposer").
+		WithArgs(
+			"--l1-eth-rpc", Connect(o.L1Node, "http"),
+			"--rollup-rpc", Connect(o.RollupNode, "http"),
+			"--game-factory-address", o.DisputeGameFactory,
+			"--game-type", opPermissionedGameType,
+			"--proposal-interval", o.ProposalInterval.String(),
+			// the L1 chain takes too long to finalize, propose the safe L2 blocks instead
+			"--allow-non-finalized=true",
+			"--poll-interval=1s",
+			"--num-confirmations=1",
+			"--private-key="+opProposerPrivateKey,
+		)
+}
+
+func (o *OpProposer) Name() string {
+	return "op-proposer"
+}
+
+var _ ServiceWatchdog = &OpProposer{}
+
+func (o *OpProposer) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
+	l1URL := fmt.Sprintf("http://localhost:%d", instance.manifest.MustGetService(o.L1Node).MustGetPort("http").HostPort)
+	return watchDisputeGames(out, l1URL, o.DisputeGameFactory)
+}
+
+// OpChallenger plays the dispute games created by the op-proposer
+type OpChallenger struct {
+	L1Node     string
+	L1Beacon   string
+	L2Node     string
+	RollupNode string
+
+	// DisputeGameFactory is the L1 address of the dispute game factory
+	DisputeGameFactory string
+
+	// PrestatesURL is the base URL of the op-program prestates used by cannon
+	PrestatesURL string
+}
+
+// defaultOpPrestatesURL is the base URL of the op-program prestates published by OP Labs
+const defaultOpPrestatesURL = "https://storage.googleapis.com/oplabs-network-data/proofs/op-program/cannon"
+
+func (o *OpChallenger) Run(service *Service, ctx *ExContext) {
+	if o.PrestatesURL == "" {
+		o.PrestatesURL = defaultOpPrestatesURL
+	}
+	service.
+		WithImage("us-docker.pkg.dev/oplabs-tools-artifacts/images/op-challenger").
+		WithTag("v1.5.0").
+		WithEntrypoint("op-challenger").
+		WithArgs(
+			"--l1-eth-rpc", Connect(o.L1Node, "http"),
+			"--l1-beacon", Connect(o.L1Beacon, "http"),
+			"--l2-eth-rpc", Connect(o.L2Node, "http"),
+			"--rollup-rpc", Connect(o.RollupNode, "http"),
+			"--game-factory-address", o.DisputeGameFactory,
+			"--trace-type", "permissioned",
+			"--datadir", "/data_challenger",
+			"--cannon-bin", "/usr/local/bin/cannon",
+			"--cannon-server", "/usr/local/bin/op-program",
+			"--cannon-prestates-url", o.PrestatesURL,
+			"--cannon-rollup-config", "/data/rollup.json",
+			"--cannon-l2-genesis", "/data/l2-genesis.json",
+			"--num-confirmations=1",
+			"--private-key="+opChallengerPrivateKey,
+		).
+		WithArtifact("/data/rollup.json", "rollup.json").
+		WithArtifact("/data/l2-genesis.json", "l2-genesis.json").
+		WithVolume("data", "/data_challenger")
+}
+
+func (o *OpChallenger) Name() string {
+	return "op-challenger"
+}
+
 type OpNode struct {
 	L1Node   string
 	L1Beacon string
 	L2Node   string
+
+	// RollupConfig is the rollup config artifact of the chain, it defaults to rollup.json
+	RollupConfig string
 }
 
 func (o *OpNode) Run(service *Service, ctx *ExContext) {
+	if o.RollupConfig == "" {
+		o.RollupConfig = "rollup.json"
+	}
 	service.
 		WithImage("us-docker.pkg.dev/oplabs-tools-artifacts/images/op-node").
 		WithTag("v1.13.0-rc.1").
@@ -110,7 +310,7 @@ func (o *OpNode) Run(service *Service, ctx *ExContext) {
 			"--safedb.path", "/data_db",
 		).
 		WithArtifact("/data/jwtsecret", "jwtsecret").
-		WithArtifact("/data/rollup.json", "rollup.json").
+		WithArtifact("/data/rollup.json", o.RollupConfig).
 		WithVolume("data", "/data_db")
 }
 
@@ -118,9 +318,19 @@ func (o *OpNode) Name() string {
 	return "op-node"
 }
 
+var _ ServiceWatchdog = &OpNode{}
+
+func (o *OpNode) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
+	opNodeURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
+	return watchSyncStatus(out, opNodeURL)
+}
+
 type OpGeth struct {
 	UseDeterministicP2PKey bool
 
+	// Genesis is the L2 genesis artifact of the chain, it defaults to l2-genesis.json
+	Genesis string
+
 	// outputs
 	Enode string
 }
@@ -143,6 +353,9 @@ func logLevelToGethVerbosity(logLevel LogLevel) string {
 }
 
 func (o *OpGeth) Run(service *Service, ctx *ExContext) {
+	if o.Genesis == "" {
+		o.Genesis = "l2-genesis.json"
+	}
 	var nodeKeyFlag string
 	if o.UseDeterministicP2PKey {
 		nodeKeyFlag = "--nodekey /data/deterministic_p2p_key.txt "
@@ -187,7 +400,7 @@ func (o *OpGeth) Run(service *Service, ctx *ExContext) {
 				"--metrics.port "+`{{Port "metrics" 6061}}`,
 		).
 		WithVolume("data", "/data_opgeth").
-		WithArtifact("/data/l2-genesis.json", "l2-genesis.json").
+		WithArtifact("/data/l2-genesis.json", o.Genesis).
 		WithArtifact("/data/jwtsecret", "jwtsecret").
 		WithArtifact("/data/deterministic_p2p_key.txt", "deterministic_p2p_key.txt")
 }
@@ -214,7 +427,7 @@ func (o *OpGeth) Ready(instance *instance) error {
 
 var _ ServiceWatchdog = &OpGeth{}
 
-func (o *OpGeth) Watchdog(out io.Writer, instance *instance, ctx context.Context) error {
+func (o *OpGeth) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
 	gethURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
 	return watchChainHead(out, gethURL, 2*time.Second)
 }
@@ -222,6 +435,20 @@ func (o *OpGeth) Watchdog(out io.Writer, instance *instance, ctx context.Context
 type RethEL struct {
 	UseRethForValidation bool
 	UseNativeReth        bool
+
+	// Bootnode captures the enode of the node once it is ready so that the other
+	// nodes can peer with it
+	Bootnode bool
+
+	// TrustedPeer is the execution client service to peer with once both nodes are ready.
+	// It must be a bootnode.
+	TrustedPeer string
+
+	// outputs
+	Enode string
+
+	// peerEnode is the enode of the bootnode in the network of the runner
+	peerEnode string
 }
 
 func (r *RethEL) ReleaseArtifact() *release {
@@ -260,6 +487,12 @@ func logLevelToRethVerbosity(logLevel LogLevel) string {
 }
 
 func (r *RethEL) Run(svc *Service, ctx *ExContext) {
+	// the p2p port is only reachable by other containers in a multi-node setup
+	p2pAddr := "127.0.0.1"
+	if r.Bootnode || r.TrustedPeer != "" {
+		p2pAddr = "0.0.0.0"
+	}
+
 	// start the reth el client
 	svc.
 		WithImage("ghcr.io/paradigmxyz/reth").
@@ -271,13 +504,13 @@ func (r *RethEL) Run(svc *Service, ctx *ExContext) {
 			"--datadir", "/data_reth",
 			"--color", "never",
 			"--ipcpath", "/data_reth/reth.ipc",
-			"--addr", "127.0.0.1",
+			"--addr", p2pAddr,
 			"--port", `{{Port "rpc" 30303}}`,
 			// "--disable-discovery",
 			// http config
 			"--http",
 			"--http.addr", "0.0.0.0",
-			"--http.api", "admin,eth,web3,net,rpc,mev,flashbots",
+			"--http.api", "admin,eth,web3,net,rpc,mev,flashbots,debug,trace",
 			"--http.port", `{{Port "http" 8545}}`,
 			"--authrpc.port", `{{Port "authrpc" 8551}}`,
 			"--authrpc.addr", "0.0.0.0",
@@ -301,19 +534,256 @@ func (r *RethEL) Name() string {
 	return "reth"
 }
 
+var _ ServiceReady = &RethEL{}
+
+func (r *RethEL) Ready(instance *instance) error {
+	if !r.Bootnode && r.TrustedPeer == "" {
+		// the enode is only required in a multi-node setup
+		return nil
+	}
+
+	elURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
+	enode, err := fetchEnode(elURL)
+	if err != nil {
+		return fmt.Errorf("failed to get the enode of %s: %w", instance.service.Name, err)
+	}
+
+	enodeID := strings.Split(strings.TrimPrefix(enode, "enode://"), "@")[0]
+	r.Enode = fmt.Sprintf("enode://%s@127.0.0.1:%d", enodeID, instance.service.MustGetPort("rpc").HostPort)
+
+	if r.Bootnode {
+		addr, err := instance.peerAddr("rpc")
+		if err != nil {
+			return err
+		}
+		r.peerEnode = fmt.Sprintf("enode://%s@%s", enodeID, addr)
+	}
+	if r.TrustedPeer != "" {
+		return peerWithBootnode(instance, elURL, r.TrustedPeer)
+	}
+	return nil
+}
+
+func (r *RethEL) bootnodeEnode() string {
+	return r.peerEnode
+}
+
+// elBootnode is an execution client that the other nodes peer with once it is ready
+type elBootnode interface {
+	bootnodeEnode() string
+}
+
+var (
+	_ elBootnode = &RethEL{}
+	_ elBootnode = &GethEL{}
+)
+
+// isELBootnode returns whether the component is an execution client bootnode. Its ready
+// hook needs the address of the node in the network of the runner (see peerAddr).
+func isELBootnode(component ServiceGen) bool {
+	switch c := component.(type) {
+	case *RethEL:
+		return c.Bootnode
+	case *GethEL:
+		return c.Bootnode
+	}
+	return false
+}
+
+// peerWithBootnode adds the enode captured by the ready hook of the bootnode as a trusted
+// peer of the execution client. The ready hook of the bootnode must run first.
+func peerWithBootnode(instance *instance, elURL, bootnode string) error {
+	svc, ok := instance.manifest.GetService(bootnode)
+	if !ok {
+		return fmt.Errorf("trusted peer %s of %s not found", bootnode, instance.service.Name)
+	}
+	node, ok := svc.component.(elBootnode)
+	if !ok || node.bootnodeEnode() == "" {
+		return fmt.Errorf("trusted peer %s of %s is not a bootnode", bootnode, instance.service.Name)
+	}
+	if err := addTrustedPeer(elURL, node.bootnodeEnode()); err != nil {
+		return fmt.Errorf("failed to peer %s with %s: %w", instance.service.Name, bootnode, err)
+	}
+	return nil
+}
+
 var _ ServiceWatchdog = &RethEL{}
 
-func (r *RethEL) Watchdog(out io.Writer, instance *instance, ctx context.Context) error {
+func (r *RethEL) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
 	rethURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
 	return watchChainHead(out, rethURL, 12*time.Second)
 }
 
+type GethEL struct {
+	// Bootnode captures the enode of the node once it is ready so that the other
+	// nodes can peer with it
+	Bootnode bool
+
+	// peerEnode is the enode of the bootnode in the network of the runner
+	peerEnode string
+}
+
+func (g *GethEL) Run(svc *Service, ctx *ExContext) {
+	svc.
+		WithImage("ethereum/client-go").
+		WithTag("v1.15.10").
+		WithEntrypoint("/bin/sh").
+		WithLabel("metrics_path", "/debug/metrics/prometheus").
+		WithArgs(
+			"-c",
+			"geth init --datadir /data_geth /data/genesis.json && "+
+				"exec geth "+
+				"--datadir /data_geth "+
+				"--verbosity "+logLevelToGethVerbosity(ctx.LogLevel)+" "+
+				"--syncmode full "+
+				"--http "+
+				"--http.corsdomain \"*\" "+
+				"--http.vhosts \"*\" "+
+				"--http.addr 0.0.0.0 "+
+				"--http.port "+`{{Port "http" 8545}} `+
+				"--http.api admin,eth,web3,net,debug,txpool "+
+				"--ws "+
+				"--ws.addr 0.0.0.0 "+
+				"--ws.port "+`{{Port "ws" 8546}} `+
+				"--ws.origins \"*\" "+
+				"--ws.api eth,web3,net,debug,txpool "+
+				"--authrpc.addr 0.0.0.0 "+
+				"--authrpc.port "+`{{Port "authrpc" 8551}} `+
+				"--authrpc.vhosts \"*\" "+
+				"--authrpc.jwtsecret /data/jwtsecret "+
+				"--port "+`{{Port "rpc" 30303}} `+
+				"--nodiscover "+
+				"--metrics "+
+				"--metrics.addr 0.0.0.0 "+
+				"--metrics.port "+`{{Port "metrics" 6061}}`,
+		).
+		WithArtifact("/data/genesis.json", "genesis.json").
+		WithArtifact("/data/jwtsecret", "jwtsecret").
+		WithVolume("data", "/data_geth")
+}
+
+func (g *GethEL) Name() string {
+	return "geth"
+}
+
+var _ ServiceReady = &GethEL{}
+
+func (g *GethEL) Ready(instance *instance) error {
+	if !g.Bootnode {
+		return nil
+	}
+
+	gethURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
+	enode, err := fetchEnode(gethURL)
+	if err != nil {
+		return fmt.Errorf("failed to get the enode of %s: %w", instance.service.Name, err)
+	}
+	addr, err := instance.peerAddr("rpc")
+	if err != nil {
+		return err
+	}
+	enodeID := strings.Split(strings.TrimPrefix(enode, "enode://"), "@")[0]
+	g.peerEnode = fmt.Sprintf("enode://%s@%s", enodeID, addr)
+	return nil
+}
+
+func (g *GethEL) bootnodeEnode() string {
+	return g.peerEnode
+}
+
+var _ ServiceWatchdog = &GethEL{}
+
+func (g *GethEL) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
+	gethURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
+	return watchChainHead(out, gethURL, 12*time.Second)
+}
+
+type NethermindEL struct{}
+
+func logLevelToNethermindLevel(logLevel LogLevel) string {
+	switch logLevel {
+	case LevelTrace:
+		return "TRACE"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	case LevelInfo:
+		fallthrough
+	default:
+		return "INFO"
+	}
+}
+
+func (n *NethermindEL) Run(svc *Service, ctx *ExContext) {
+	// nethermind does not support the geth genesis format, it uses the
+	// chainspec.json artifact generated from the same genesis
+	svc.
+		WithImage("nethermind/nethermind").
+		WithTag("1.31.10").
+		WithEntrypoint("/nethermind/nethermind").
+		WithArgs(
+			"--config", "none",
+			"--datadir", "/data_nethermind",
+			"--log", logLevelToNethermindLevel(ctx.LogLevel),
+			"--Init.ChainSpecPath", "/data/chainspec.json",
+			"--Init.DiscoveryEnabled", "false",
+			"--Sync.SnapSync", "false",
+			"--JsonRpc.Enabled", "true",
+			"--JsonRpc.Host", "0.0.0.0",
+			"--JsonRpc.Port", `{{Port "http" 8545}}`,
+			"--JsonRpc.WebSocketsPort", `{{Port "ws" 8546}}`,
+			"--JsonRpc.EnabledModules", "Admin,Eth,Net,Web3,Debug,TxPool",
+			"--JsonRpc.EngineHost", "0.0.0.0",
+			"--JsonRpc.EnginePort", `{{Port "authrpc" 8551}}`,
+			"--JsonRpc.JwtSecretFile", "/data/jwtsecret",
+			"--Network.P2PPort", `{{Port "rpc" 30303}}`,
+			"--Network.DiscoveryPort", `{{PortUDP "rpc" 30303}}`,
+			"--Metrics.Enabled", "true",
+			"--Metrics.ExposeHost", "0.0.0.0",
+			"--Metrics.ExposePort", `{{Port "metrics" 9091}}`,
+		).
+		WithArtifact("/data/chainspec.json", "chainspec.json").
+		WithArtifact("/data/jwtsecret", "jwtsecret").
+		WithVolume("data", "/data_nethermind")
+}
+
+func (n *NethermindEL) Name() string {
+	return "nethermind"
+}
+
+var _ ServiceWatchdog = &NethermindEL{}
+
+func (n *NethermindEL) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
+	nethermindURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
+	return watchChainHead(out, nethermindURL, 12*time.Second)
+}
+
 type LighthouseBeaconNode struct {
 	ExecutionNode string
 	MevBoostNode  string
+
+	// Bootnode is the beacon node service to peer with once both nodes are ready.
+	// Its enr is captured by its ready hook.
+	Bootnode string
+
+	// TargetPeers is the number of peers the node tries to keep. It is zero in a single node setup.
+	TargetPeers uint64
+
+	// outputs
+	ENR string
 }
 
 func (l *LighthouseBeaconNode) Run(svc *Service, ctx *ExContext) {
+	// in a multi-node setup the enr advertises the address of the node in the network
+	// (lighthouse resolves the service name) so that the other nodes can connect to it
+	enrAddr := "127.0.0.1"
+	if l.Bootnode != "" || l.TargetPeers != 0 {
+		enrAddr = svc.Name
+	}
+
 	svc.
 		WithImage("sigp/lighthouse").
 		WithTag("v7.0.0-beta.0").
@@ -325,7 +795,7 @@ func (l *LighthouseBeaconNode) Run(svc *Service, ctx *ExContext) {
 			"--enable-private-discovery",
 			"--disable-peer-scoring",
 			"--staking",
-			"--enr-address", "127.0.0.1",
+			"--enr-address", enrAddr,
 			"--enr-udp-port", `{{PortUDP "p2p" 9000}}`,
 			"--enr-tcp-port", `{{Port "p2p" 9000}}`,
 			"--enr-quic-port", `{{Port "quic-p2p" 9100}}`,
@@ -336,7 +806,7 @@ func (l *LighthouseBeaconNode) Run(svc *Service, ctx *ExContext) {
 			"--http-address", "0.0.0.0",
 			"--http-allow-origin", "*",
 			"--disable-packet-filter",
-			"--target-peers", "0",
+			"--target-peers", strconv.FormatUint(l.TargetPeers, 10),
 			"--execution-endpoint", Connect(l.ExecutionNode, "authrpc"),
 			"--execution-jwt", "/data/jwtsecret",
 			"--always-prepare-payload",
@@ -367,11 +837,57 @@ func (l *LighthouseBeaconNode) Name() string {
 	return "lighthouse-beacon-node"
 }
 
+var _ ServiceWatchdog = &LighthouseBeaconNode{}
+
+func (l *LighthouseBeaconNode) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
+	beaconNodeURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
+	return watchFinality(out, beaconNodeURL, true)
+}
+
+var _ ServiceReady = &LighthouseBeaconNode{}
+
+func (l *LighthouseBeaconNode) Ready(instance *instance) error {
+	if l.Bootnode == "" && l.TargetPeers == 0 {
+		// the enr is only required in a multi-node setup
+		return nil
+	}
+
+	beaconURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
+	enr, err := fetchBeaconENR(beaconURL)
+	if err != nil {
+		return fmt.Errorf("failed to get the enr of %s: %w", instance.service.Name, err)
+	}
+	l.ENR = enr
+
+	if l.Bootnode != "" {
+		svc, ok := instance.manifest.GetService(l.Bootnode)
+		if !ok {
+			return fmt.Errorf("bootnode %s of %s not found", l.Bootnode, instance.service.Name)
+		}
+		bootnode, ok := svc.component.(*LighthouseBeaconNode)
+		if !ok || bootnode.ENR == "" {
+			return fmt.Errorf("bootnode %s of %s has no enr", l.Bootnode, instance.service.Name)
+		}
+		if err := addBeaconPeer(beaconURL, bootnode.ENR); err != nil {
+			return fmt.Errorf("failed to peer %s with %s: %w", instance.service.Name, l.Bootnode, err)
+		}
+	}
+	return nil
+}
+
 type LighthouseValidator struct {
 	BeaconNode string
+
+	// Keystore is the artifact with the validator keys. Defaults to 'data_validator'.
+	Keystore string
 }
 
 func (l *LighthouseValidator) Run(service *Service, ctx *ExContext) {
+	keystore := l.Keystore
+	if keystore == "" {
+		keystore = "data_validator"
+	}
+
 	// start validator client
 	service.
 		WithImage("sigp/lighthouse").
@@ -387,7 +903,7 @@ func (l *LighthouseValidator) Run(service *Service, ctx *ExContext) {
 			"--builder-proposals",
 			"--prefer-builder-proposals",
 		).
-		WithArtifact("/data/validator", "data_validator").
+		WithArtifact("/data/validator", keystore).
 		WithArtifact("/data/testnet-dir", "testnet")
 }
 
@@ -395,6 +911,195 @@ func (l *LighthouseValidator) Name() string {
 	return "lighthouse-validator"
 }
 
+// defaultFeeRecipient is the fee recipient of the blocks proposed by the validators
+const defaultFeeRecipient = "0x690B9A9E9aa1C9dB991C7721a92d351Db4FaC990"
+
+type PrysmBeaconNode struct {
+	ExecutionNode string
+	MevBoostNode  string
+}
+
+func (p *PrysmBeaconNode) Run(svc *Service, ctx *ExContext) {
+	svc.
+		WithImage("gcr.io/offchainlabs/prysm/beacon-chain").
+		WithTag("v5.3.2").
+		WithArgs(
+			"--datadir", "/data_beacon",
+			"--chain-config-file", "/data/testnet-dir/config.yaml",
+			"--genesis-state", "/data/testnet-dir/genesis.ssz",
+			"--contract-deployment-block", "0",
+			"--accept-terms-of-use",
+			"--verbosity", string(ctx.LogLevel),
+			"--no-discovery",
+			"--min-sync-peers", "0",
+			"--minimum-peers-per-subnet", "0",
+			"--subscribe-all-subnets",
+			"--p2p-static-id",
+			"--p2p-tcp-port", `{{Port "p2p" 13000}}`,
+			"--p2p-udp-port", `{{PortUDP "discovery" 12000}}`,
+			"--rpc-host", "0.0.0.0",
+			"--rpc-port", `{{Port "rpc" 4000}}`,
+			"--http-host", "0.0.0.0",
+			"--http-port", `{{Port "http" 3500}}`,
+			"--http-cors-domain", "*",
+			"--execution-endpoint", Connect(p.ExecutionNode, "authrpc"),
+			"--jwt-secret", "/data/jwtsecret",
+			"--suggested-fee-recipient", defaultFeeRecipient,
+		).
+		WithArtifact("/data/testnet-dir", "testnet").
+		WithArtifact("/data/jwtsecret", "jwtsecret").
+		WithVolume("data", "/data_beacon").
+		WithReady(ReadyCheck{
+			QueryURL:    "http://localhost:3500/eth/v1/node/syncing",
+			Interval:    1 * time.Second,
+			Timeout:     30 * time.Second,
+			Retries:     3,
+			StartPeriod: 1 * time.Second,
+		})
+
+	if p.MevBoostNode != "" {
+		svc.WithArgs("--http-mev-relay", Connect(p.MevBoostNode, "http"))
+	}
+}
+
+func (p *PrysmBeaconNode) Name() string {
+	return "prysm-beacon-node"
+}
+
+var _ ServiceWatchdog = &PrysmBeaconNode{}
+
+func (p *PrysmBeaconNode) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
+	beaconNodeURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
+	return watchFinality(out, beaconNodeURL, false)
+}
+
+type PrysmValidator struct {
+	BeaconNode string
+
+	// Keystore is the artifact with the prysm wallet. Defaults to 'data_validator'.
+	Keystore string
+}
+
+func (p *PrysmValidator) Run(service *Service, ctx *ExContext) {
+	keystore := p.Keystore
+	if keystore == "" {
+		keystore = "data_validator"
+	}
+
+	// the prysm validator connects to the gRPC endpoint of a prysm beacon node
+	service.
+		WithImage("gcr.io/offchainlabs/prysm/validator").
+		WithTag("v5.3.2").
+		WithArgs(
+			"--datadir", "/data_validator",
+			"--wallet-dir", "/data/validator/wallet",
+			"--wallet-password-file", "/data/validator/wallet-password.txt",
+			"--chain-config-file", "/data/testnet-dir/config.yaml",
+			"--accept-terms-of-use",
+			"--verbosity", string(ctx.LogLevel),
+			"--beacon-rpc-provider", ConnectRaw(p.BeaconNode, "rpc", ""),
+			"--beacon-rest-api-provider", Connect(p.BeaconNode, "http"),
+			"--suggested-fee-recipient", defaultFeeRecipient,
+			"--enable-builder",
+		).
+		WithArtifact("/data/validator", keystore).
+		WithArtifact("/data/testnet-dir", "testnet").
+		WithVolume("data", "/data_validator")
+}
+
+func (p *PrysmValidator) Name() string {
+	return "prysm-validator"
+}
+
+type TekuBeaconNode struct {
+	ExecutionNode string
+	MevBoostNode  string
+}
+
+func (t *TekuBeaconNode) Run(svc *Service, ctx *ExContext) {
+	svc.
+		WithImage("consensys/teku").
+		WithTag("25.4.1").
+		WithArgs(
+			"--network", "/data/testnet-dir/config.yaml",
+			"--initial-state", "/data/testnet-dir/genesis.ssz",
+			"--data-path", "/data_beacon",
+			"--logging", strings.ToUpper(string(ctx.LogLevel)),
+			"--ignore-weak-subjectivity-period-enabled", "true",
+			"--p2p-port", `{{Port "p2p" 9000}}`,
+			"--p2p-discovery-enabled", "false",
+			"--p2p-peer-lower-bound", "0",
+			"--rest-api-enabled", "true",
+			"--rest-api-interface", "0.0.0.0",
+			"--rest-api-port", `{{Port "http" 3500}}`,
+			"--rest-api-host-allowlist", "*",
+			"--ee-endpoint", Connect(t.ExecutionNode, "authrpc"),
+			"--ee-jwt-secret-file", "/data/jwtsecret",
+			"--validators-proposer-default-fee-recipient", defaultFeeRecipient,
+		).
+		WithArtifact("/data/testnet-dir", "testnet").
+		WithArtifact("/data/jwtsecret", "jwtsecret").
+		WithVolume("data", "/data_beacon").
+		WithReady(ReadyCheck{
+			QueryURL:    "http://localhost:3500/eth/v1/node/syncing",
+			Interval:    1 * time.Second,
+			Timeout:     30 * time.Second,
+			Retries:     3,
+			StartPeriod: 1 * time.Second,
+		})
+
+	if t.MevBoostNode != "" {
+		svc.WithArgs("--builder-endpoint", Connect(t.MevBoostNode, "http"))
+	}
+}
+
+func (t *TekuBeaconNode) Name() string {
+	return "teku-beacon-node"
+}
+
+var _ ServiceWatchdog = &TekuBeaconNode{}
+
+func (t *TekuBeaconNode) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
+	beaconNodeURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
+	return watchFinality(out, beaconNodeURL, false)
+}
+
+type TekuValidator struct {
+	BeaconNode string
+
+	// Keystore is the artifact with the validator keys. Defaults to 'data_validator'.
+	Keystore string
+}
+
+func (t *TekuValidator) Run(service *Service, ctx *ExContext) {
+	keystore := t.Keystore
+	if keystore == "" {
+		keystore = "data_validator"
+	}
+
+	service.
+		WithImage("consensys/teku").
+		WithTag("25.4.1").
+		WithArgs(
+			"validator-client",
+			"--network", "/data/testnet-dir/config.yaml",
+			"--data-path", "/data_validator",
+			"--logging", strings.ToUpper(string(ctx.LogLevel)),
+			"--beacon-node-api-endpoint", Connect(t.BeaconNode, "http"),
+			"--validator-keys", "/data/validator/keys:/data/validator/secrets",
+			"--validators-keystore-locking-enabled", "false",
+			"--validators-proposer-default-fee-recipient", defaultFeeRecipient,
+			"--validators-builder-registration-default-enabled", "true",
+		).
+		WithArtifact("/data/validator", keystore).
+		WithArtifact("/data/testnet-dir", "testnet").
+		WithVolume("data", "/data_validator")
+}
+
+func (t *TekuValidator) Name() string {
+	return "teku-validator"
+}
+
 type ClProxy struct {
 	PrimaryBuilder   string
 	SecondaryBuilder string
@@ -419,6 +1124,10 @@ func (c *ClProxy) Name() string {
 type MevBoostRelay struct {
 	BeaconClient     string
 	ValidationServer string
+
+	// ExecutionNode is the execution layer used by the watchdog to check that the delivered
+	// payloads are canonical. The check is skipped if it is not set.
+	ExecutionNode string
 }
 
 func (m *MevBoostRelay) Run(service *Service, ctx *ExContext) {
@@ -445,20 +1154,171 @@ func (m *MevBoostRelay) Name() string {
 
 var _ ServiceWatchdog = &MevBoostRelay{}
 
-func (m *MevBoostRelay) Watchdog(out io.Writer, instance *instance, ctx context.Context) error {
-	beaconNodeURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
+func (m *MevBoostRelay) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
+	relayURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
+
+	var elURL string
+	if m.ExecutionNode != "" {
+		elURL = fmt.Sprintf("http://localhost:%d", instance.manifest.MustGetService(m.ExecutionNode).MustGetPort("http").HostPort)
+	}
 
 	watchGroup := newWatchGroup()
 	watchGroup.watch(func() error {
-		return watchProposerPayloads(beaconNodeURL)
+		return watchRelayAudit(out, relayURL, elURL)
 	})
 	watchGroup.watch(func() error {
-		return validateProposerPayloads(out, beaconNodeURL)
+		return validateProposerPayloads(out, relayURL)
 	})
 
 	return watchGroup.wait()
 }
 
+// mevBoostRelayPubKey is the public key of the default secret key of the mev-boost-relay service
+const mevBoostRelayPubKey = "0xa1885d66bef164889a2e35845c3b626545d7b0e513efe335e97c3a45e534013fa3bc38c3b7e6143695aecc4872ac52c4"
+
+// MevBoost is the mev-boost sidecar that sits between the beacon node and the relays
+type MevBoost struct {
+	// Relays is the list of relays. Each entry is either the name of a mev-boost-relay
+	// service or the full URL of an external relay (i.e. http://0xpubkey@host:port).
+	Relays []string
+
+	// MinBid is the minimum bid (in ETH) to accept from the relays, zero to accept all the bids
+	MinBid float64
+
+	// GenesisTime and GenesisForkVersion configure mev-boost for the L1 chain
+	GenesisTime        uint64
+	GenesisForkVersion string
+
+	// Timeouts (in milliseconds) of the requests to the relays. The mev-boost defaults are used if zero.
+	TimeoutGetHeaderMs  uint64
+	TimeoutGetPayloadMs uint64
+}
+
+func (m *MevBoost) Run(service *Service, ctx *ExContext) {
+	relays := []string{}
+	for _, relay := range m.Relays {
+		if strings.Contains(relay, "://") {
+			relays = append(relays, relay)
+		} else {
+			relays = append(relays, "http://"+mevBoostRelayPubKey+"@"+ConnectRaw(relay, "http", ""))
+		}
+	}
+
+	service.
+		WithImage("flashbots/mev-boost").
+		WithTag("1.9").
+		WithArgs(
+			"-addr", `0.0.0.0:{{Port "http" 18550}}`,
+			"-loglevel", string(ctx.LogLevel),
+			"-relays", strings.Join(relays, ","),
+			"-min-bid", strconv.FormatFloat(m.MinBid, 'f', -1, 64),
+		)
+
+	if m.GenesisForkVersion != "" {
+		service.WithArgs("-genesis-fork-version", m.GenesisForkVersion)
+	}
+	if m.GenesisTime != 0 {
+		service.WithArgs("-genesis-timestamp", strconv.FormatUint(m.GenesisTime, 10))
+	}
+	if m.TimeoutGetHeaderMs != 0 {
+		service.WithArgs("-request-timeout-getheader", strconv.FormatUint(m.TimeoutGetHeaderMs, 10))
+	}
+	if m.TimeoutGetPayloadMs != 0 {
+		service.WithArgs("-request-timeout-getpayload", strconv.FormatUint(m.TimeoutGetPayloadMs, 10))
+	}
+}
+
+func (m *MevBoost) Name() string {
+	return "mev-boost"
+}
+
+// Rbuilder is the rbuilder block builder. It reads the state from the datadir and the
+// mempool from the IPC of the reth node and submits the blocks to the relay. The relay
+// and the beacon node are set in the <service>.toml artifact (see WriteConfig).
+type Rbuilder struct {
+	// ExecutionNode is the reth service that shares its datadir with the builder
+	ExecutionNode string
+	BeaconNode    string
+	Relay         string
+}
+
+func (r *Rbuilder) Run(service *Service, ctx *ExContext) {
+	service.
+		WithImage("ghcr.io/flashbots/rbuilder").
+		WithTag("v1.1.0").
+		WithArgs("run", "/data/rbuilder.toml").
+		WithPort("rpc", 8645).
+		WithPort("metrics", 6060).
+		WithArtifact("/data/rbuilder.toml", service.Name+".toml").
+		WithArtifact("/data/genesis.json", "genesis.json").
+		WithSharedVolume(r.ExecutionNode, "data", "/data_reth").
+		DependsOnRunning(r.ExecutionNode).
+		DependsOnHealthy(r.BeaconNode).
+		DependsOnRunning(r.Relay)
+}
+
+func (r *Rbuilder) Name() string {
+	return "rbuilder"
+}
+
+var _ ServiceConfig = &Rbuilder{}
+
+// WriteConfig writes the rbuilder config with the urls of the relay and the beacon node. They
+// cannot use the {{Service}} templates of the arguments, but rbuilder always runs in docker
+// (it shares the volume of the reth node) and reaches them with their names and ports.
+func (r *Rbuilder) WriteConfig(manifest *Manifest, service *Service) error {
+	relay := manifest.MustGetService(r.Relay).MustGetPort("http")
+	beacon := manifest.MustGetService(r.BeaconNode).MustGetPort("http")
+
+	config, err := applyTemplate2(rbuilderConfigContent, map[string]string{
+		"RelayURL":          fmt.Sprintf("http://%s@%s:%d", mevBoostRelayPubKey, r.Relay, relay.Port),
+		"BeaconURL":         fmt.Sprintf("http://%s:%d", r.BeaconNode, beacon.Port),
+		"RelaySecretKey":    rbuilderRelaySecretKey,
+		"CoinbaseSecretKey": strings.TrimPrefix(prefundedAccounts[rbuilderCoinbaseAccount], "0x"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate the rbuilder config: %w", err)
+	}
+	return manifest.out.WriteFile(service.Name+".toml", config)
+}
+
+// TxSpammer sends a constant load of transactions signed by the prefunded accounts.
+// The submitted and included counts are reported periodically in its logs.
+type TxSpammer struct {
+	// ELNode is the el service that receives the transactions
+	ELNode string
+
+	// BundleNode is the builder service that receives the transactions as bundles
+	// on its 'rpc' port. The transactions are sent to the ELNode if it is empty.
+	BundleNode string
+
+	TPS     uint64
+	TxTypes []string
+}
+
+func (t *TxSpammer) Run(service *Service, ctx *ExContext) {
+	service.
+		WithImage("docker.io/flashbots/playground-utils").
+		WithTag("latest").
+		WithEntrypoint("tx-spammer").
+		WithArgs(
+			"--rpc", Connect(t.ELNode, "http"),
+			"--private-keys", strings.Join(txSpammerAccounts, ","),
+			"--tps", strconv.FormatUint(t.TPS, 10),
+			"--tx-types", strings.Join(t.TxTypes, ","),
+		).
+		DependsOnRunning(t.ELNode)
+
+	if t.BundleNode != "" {
+		service.WithArgs("--bundle-rpc", Connect(t.BundleNode, "rpc")).
+			DependsOnRunning(t.BundleNode)
+	}
+}
+
+func (t *TxSpammer) Name() string {
+	return "tx-spammer"
+}
+
 type BuilderHubPostgres struct {
 }
 
@@ -574,11 +1434,99 @@ func (o *OpReth) ReleaseArtifact() *release {
 
 var _ ServiceWatchdog = &OpReth{}
 
-func (p *OpReth) Watchdog(out io.Writer, instance *instance, ctx context.Context) error {
+func (p *OpReth) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
 	rethURL := fmt.Sprintf("http://localhost:%d", instance.service.MustGetPort("http").HostPort)
 	return watchChainHead(out, rethURL, 2*time.Second)
 }
 
+// OpRbuilder is the op-rbuilder block builder of the L2
+type OpRbuilder struct {
+	// Flashblocks enables the flashblocks websocket of the builder. The sub-blocks
+	// are built every flashblocksInterval during the BlockTime (in seconds).
+	Flashblocks bool
+	BlockTime   uint64
+}
+
+func (o *OpRbuilder) Run(service *Service, ctx *ExContext) {
+	service.WithImage("ghcr.io/flashbots/op-rbuilder").
+		WithTag("v0.1.0").
+		WithArgs(
+			"node",
+			"--authrpc.port", `{{Port "authrpc" 8551}}`,
+			"--authrpc.addr", "0.0.0.0",
+			"--authrpc.jwtsecret", "/data/jwtsecret",
+			"--http",
+			"--http.addr", "0.0.0.0",
+			"--http.port", `{{Port "http" 8545}}`,
+			"--chain", "/data/l2-genesis.json",
+			"--datadir", "/data_op_rbuilder",
+			"--disable-discovery",
+			"--color", "never",
+			"--metrics", `0.0.0.0:{{Port "metrics" 9090}}`,
+			"--port", `{{Port "rpc" 30303}}`).
+		WithArtifact("/data/jwtsecret", "jwtsecret").
+		WithArtifact("/data/l2-genesis.json", "l2-genesis.json").
+		WithVolume("data", "/data_op_rbuilder")
+	if o.Flashblocks {
+		service.WithArgs(flashblocksBuilderArgs(o.BlockTime)...)
+	}
+}
+
+func (o *OpRbuilder) Name() string {
+	return "op-rbuilder"
+}
+
+// flashblocksInterval is the time between the flashblocks of the op-rbuilder builders
+const flashblocksInterval = 250 * time.Millisecond
+
+// flashblocksBuilderArgs are the arguments to enable the flashblocks websocket of
+// op-rbuilder (and the builders based on it) for the L2 block time in seconds
+func flashblocksBuilderArgs(blockTime uint64) []string {
+	if blockTime == 0 {
+		blockTime = defaultOpBlockTimeSeconds
+	}
+	return []string{
+		"--rollup.chain-block-time", strconv.FormatUint(blockTime*1000, 10),
+		"--flashblocks.enabled",
+		"--flashblocks.addr", "0.0.0.0",
+		"--flashblocks.port", `{{Port "flashblocks" 1111}}`,
+		"--flashblocks.block-time", strconv.FormatInt(flashblocksInterval.Milliseconds(), 10),
+	}
+}
+
+// FlashblocksProxy is the websocket proxy that serves the flashblocks stream of rollup-boost
+// to the clients
+type FlashblocksProxy struct {
+	Upstream string
+
+	// BlockTime is the L2 block time in seconds used by the watchdog
+	BlockTime uint64
+}
+
+func (f *FlashblocksProxy) Run(service *Service, ctx *ExContext) {
+	service.WithImage("docker.io/flashbots/flashblocks-websocket-proxy").
+		WithTag("v1.0.0").
+		WithArgs(
+			"--listen-addr", `0.0.0.0:{{Port "ws" 8080}}`,
+			"--upstream-ws", "ws://"+ConnectRaw(f.Upstream, "flashblocks", ""),
+		)
+}
+
+func (f *FlashblocksProxy) Name() string {
+	return "flashblocks-proxy"
+}
+
+var _ ServiceWatchdog = &FlashblocksProxy{}
+
+func (f *FlashblocksProxy) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
+	blockTime := f.BlockTime
+	if blockTime == 0 {
+		blockTime = defaultOpBlockTimeSeconds
+	}
+	wsURL := fmt.Sprintf("ws://localhost:%d", instance.service.MustGetPort("ws").HostPort)
+	return watchFlashblocks(out, wsURL, time.Duration(blockTime)*time.Second, flashblocksInterval)
+}
+
 type nullService struct {
 }
 
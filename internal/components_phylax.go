@@ -68,6 +68,10 @@ type OpTalos struct {
 	AssertionDA    string
 	AssexGasLimit  uint64
 	OracleContract string
+
+	// Flashblocks enables the flashblocks websocket of the builder for the BlockTime (in seconds)
+	Flashblocks bool
+	BlockTime   uint64
 }
 
 func (o *OpTalos) Run(service *Service, ctx *ExContext) {
@@ -81,6 +85,7 @@ func (o *OpTalos) Run(service *Service, ctx *ExContext) {
 			"--http",
 			"--http.addr", "0.0.0.0",
 			"--http.port", `{{Port "http" 8545}}`,
+			"--http.api", "eth,net,web3,debug,trace,txpool",
 			"--ws",
 			"--ws.origins", "*",
 			"--ws.port", `{{Port "ws" 8546}}`,
@@ -100,6 +105,9 @@ func (o *OpTalos) Run(service *Service, ctx *ExContext) {
 		WithEnv("AE_ASSERTION_GAS_LIMIT", strconv.FormatUint(o.AssexGasLimit, 10)).
 		WithEnv("AE_BLOCK_TAG", "latest").
 		WithEnv("RUST_LOG", logLevelToTalosVerbosity(ctx.LogLevel))
+	if o.Flashblocks {
+		service.WithArgs(flashblocksBuilderArgs(o.BlockTime)...)
+	}
 	if ctx.AlloyEnabled {
 		service.WithEnv("OTEL_EXPORTER_OTLP_ENDPOINT", Connect("grafana-alloy", "otlp-http")).
 			WithEnv("OTEL_ENVIRONMENT_NAME", "PCL_TALOS").
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// ContainerRuntime is the engine used to run the containers of the local runners
+type ContainerRuntime string
+
+var (
+	RuntimeDocker ContainerRuntime = "docker"
+	RuntimePodman ContainerRuntime = "podman"
+)
+
+func (r *ContainerRuntime) Unmarshal(s string) error {
+	switch s {
+	case "", "docker":
+		*r = RuntimeDocker
+	case "podman":
+		*r = RuntimePodman
+	default:
+		return fmt.Errorf("invalid container runtime: %s", s)
+	}
+	return nil
+}
+
+// hostGateway returns the address that resolves host.docker.internal to the host machine
+// inside the containers.
+func (r ContainerRuntime) hostGateway() string {
+	if r == RuntimePodman {
+		// Podman resolves the special 'host-gateway' value to the address of the host. In rootless
+		// mode this is the address of the user network namespace and not the docker0 bridge.
+		return "host-gateway"
+	}
+	// On Linux, you can use the IP address 172.17.0.1 to access the host.
+	return "172.17.0.1"
+}
+
+// composeCommand returns the command (and the environment) to run docker compose on this runtime
+func (r ContainerRuntime) composeCommand(args ...string) ([]string, []string, error) {
+	if r == RuntimePodman {
+		host, err := podmanHost()
+		if err != nil {
+			return nil, nil, err
+		}
+		// 'podman compose' delegates to an external compose provider which talks
+		// to the Podman socket through the docker API.
+		return append([]string{"podman", "compose"}, args...), append(os.Environ(), "DOCKER_HOST="+host), nil
+	}
+	return append([]string{"docker", "compose"}, args...), nil, nil
+}
+
+// newContainerClient returns a docker API client for the given runtime
+func newContainerClient(runtime ContainerRuntime) (*client.Client, error) {
+	if runtime != RuntimePodman {
+		return newDockerClient()
+	}
+
+	host, err := podmanHost()
+	if err != nil {
+		return nil, err
+	}
+	client, err := client.NewClientWithOpts(client.WithHost(host), client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create podman client: %w", err)
+	}
+	return client, nil
+}
+
+// podmanHost discovers the address of the Podman API socket. It uses CONTAINER_HOST
+// or DOCKER_HOST if they are set, otherwise it looks for the rootless socket
+// of the current user and then the rootful one.
+func podmanHost() (string, error) {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host, nil
+	}
+	if host := os.Getenv("DOCKER_HOST"); host != "" && strings.Contains(host, "podman") {
+		return host, nil
+	}
+
+	candidates := []string{}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		candidates = append(candidates, filepath.Join(runtimeDir, "podman", "podman.sock"))
+	}
+	candidates = append(candidates,
+		fmt.Sprintf("/run/user/%d/podman/podman.sock", os.Getuid()),
+		"/run/podman/podman.sock",
+	)
+
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return "unix://" + path, nil
+		}
+	}
+	return "", fmt.Errorf("podman socket not found in %s, start it with 'systemctl --user start podman.socket'", strings.Join(candidates, ", "))
+}
+
+// isRootless returns whether the runtime runs the containers without root privileges
+func isRootless(client *client.Client) (bool, error) {
+	info, err := client.Info(context.Background())
+	if err != nil {
+		return false, fmt.Errorf("failed to get runtime info: %w", err)
+	}
+	for _, opt := range info.SecurityOptions {
+		if strings.Contains(opt, "name=rootless") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// unprivilegedPortStart returns the first port that can be bound without root privileges.
+// Rootless runtimes cannot publish ports below this value.
+func unprivilegedPortStart() int {
+	data, err := os.ReadFile("/proc/sys/net/ipv4/ip_unprivileged_port_start")
+	if err != nil {
+		return 1024
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 1024
+	}
+	return port
+}
@@ -0,0 +1,320 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+)
+
+const (
+	ServicePhasePull       = "pull"
+	ServicePhaseCreate     = "create"
+	ServicePhaseStart      = "start"
+	ServicePhaseDependency = "dependency"
+)
+
+// ServiceError is the error returned by the docker runner when a service cannot be started
+type ServiceError struct {
+	// Service is the name of the service that failed
+	Service string
+
+	// Phase is the step of the deployment that failed (pull, create, start or dependency)
+	Phase string
+
+	Err error
+}
+
+func (s *ServiceError) Error() string {
+	return fmt.Sprintf("service %s failed (%s): %v", s.Service, s.Phase, s.Err)
+}
+
+func (s *ServiceError) Unwrap() error {
+	return s.Err
+}
+
+// DockerRunner is a local runner that creates the network and the containers directly
+// with the Docker API instead of using docker compose. It waits for the DependsOn conditions
+// of each service before starting it. The status tracking, the host services and the cleanup
+// are the same as the LocalRunner.
+type DockerRunner struct {
+	*LocalRunner
+}
+
+func NewDockerRunner(cfg *LocalRunnerConfig) (*DockerRunner, error) {
+	localRunner, err := NewLocalRunner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &DockerRunner{LocalRunner: localRunner}, nil
+}
+
+func (d *DockerRunner) Run() error {
+	go d.trackContainerStatusAndLogs()
+
+	d.reserveServicePorts()
+	d.initTaskLogs()
+
+	ctx := context.Background()
+	if err := d.ensureNetwork(ctx); err != nil {
+		return err
+	}
+
+	// First start the services that are running in docker
+	if err := d.startContainers(ctx); err != nil {
+		return err
+	}
+
+	// register the session so that it can be found later on by the 'status', 'logs' and 'stop' commands
+	if err := d.saveSession(); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	// Second, start the services that are running on the host machine
+	return d.runHostServices()
+}
+
+// ensureNetwork creates the network for the services if it does not exist yet
+func (d *DockerRunner) ensureNetwork(ctx context.Context) error {
+	exists, created, err := inspectSessionNetwork(d.client, d.networkName)
+	if err != nil {
+		return err
+	}
+	d.createdNetwork = created
+	if exists {
+		return nil
+	}
+
+	if _, err := d.client.NetworkCreate(ctx, d.networkName, network.CreateOptions{
+		Labels: map[string]string{"playground": "true"},
+	}); err != nil {
+		return fmt.Errorf("failed to create network %s: %w", d.networkName, err)
+	}
+	return nil
+}
+
+// containerResult is the outcome of starting the container of a service.
+// done is closed once the container is started or it failed to start.
+type containerResult struct {
+	id   string
+	err  error
+	done chan struct{}
+}
+
+// startContainers creates and starts the containers of all the services. Each service
+// waits for its dependencies to be running (or healthy) before it is started.
+func (d *DockerRunner) startContainers(ctx context.Context) error {
+	results := map[string]*containerResult{}
+	for _, svc := range d.manifest.services {
+		if d.isHostService(svc.Name) {
+			continue
+		}
+		results[svc.Name] = &containerResult{done: make(chan struct{})}
+	}
+
+	var wg sync.WaitGroup
+	for _, svc := range d.manifest.services {
+		res, ok := results[svc.Name]
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(svc *Service, res *containerResult) {
+			defer wg.Done()
+			defer close(res.done)
+
+			res.id, res.err = d.startContainer(ctx, svc, results)
+		}(svc, res)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, svc := range d.manifest.services {
+		if res, ok := results[svc.Name]; ok && res.err != nil {
+			errs = append(errs, res.err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d *DockerRunner) startContainer(ctx context.Context, s *Service, results map[string]*containerResult) (string, error) {
+	imageName := fmt.Sprintf("%s:%s", s.Image, s.Tag)
+	if err := d.pullImage(ctx, imageName); err != nil {
+		return "", &ServiceError{Service: s.Name, Phase: ServicePhasePull, Err: err}
+	}
+
+	config, hostConfig, err := d.toContainerConfig(s)
+	if err != nil {
+		return "", &ServiceError{Service: s.Name, Phase: ServicePhaseCreate, Err: err}
+	}
+
+	// wait for the dependencies before creating the container
+	for _, dep := range s.DependsOn {
+		depResult, ok := results[dep.Name]
+		if !ok {
+			// dependencies on host services are not tracked
+			continue
+		}
+		<-depResult.done
+		if depResult.err != nil {
+			return "", &ServiceError{Service: s.Name, Phase: ServicePhaseDependency, Err: fmt.Errorf("dependency %s failed to start", dep.Name)}
+		}
+		if dep.Condition == DependsOnConditionHealthy {
+			if err := d.waitForHealthy(ctx, depResult.id); err != nil {
+				return "", &ServiceError{Service: s.Name, Phase: ServicePhaseDependency, Err: fmt.Errorf("dependency %s is not healthy: %w", dep.Name, err)}
+			}
+		}
+	}
+
+	networkConfig := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			// use the name of the service as the DNS name inside the network
+			d.networkName: {Aliases: []string{s.Name}},
+		},
+	}
+
+	containerName := fmt.Sprintf("%s-%s", s.Name, d.sessionID[:8])
+	resp, err := d.client.ContainerCreate(ctx, config, hostConfig, networkConfig, nil, containerName)
+	if err != nil {
+		return "", &ServiceError{Service: s.Name, Phase: ServicePhaseCreate, Err: err}
+	}
+	if err := d.client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return "", &ServiceError{Service: s.Name, Phase: ServicePhaseStart, Err: err}
+	}
+	return resp.ID, nil
+}
+
+// pullImage pulls the image if it is not available locally
+func (d *DockerRunner) pullImage(ctx context.Context, imageName string) error {
+	_, err := d.client.ImageInspect(ctx, imageName)
+	if err == nil {
+		return nil
+	}
+	if !client.IsErrNotFound(err) {
+		return err
+	}
+
+	reader, err := d.client.ImagePull(ctx, imageName, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", imageName, err)
+	}
+	defer reader.Close()
+
+	// the pull is only completed once the progress output is fully read
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", imageName, err)
+	}
+	return nil
+}
+
+// waitForHealthy waits until the health check of the container passes
+func (d *DockerRunner) waitForHealthy(ctx context.Context, containerID string) error {
+	for {
+		info, err := d.client.ContainerInspect(ctx, containerID)
+		if err != nil {
+			return err
+		}
+		if !info.State.Running {
+			return fmt.Errorf("container exited with code %d", info.State.ExitCode)
+		}
+		if info.State.Health == nil {
+			return fmt.Errorf("container does not have a health check")
+		}
+		switch info.State.Health.Status {
+		case container.Healthy:
+			return nil
+		case container.Unhealthy:
+			return fmt.Errorf("container is unhealthy")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+func (d *DockerRunner) toContainerConfig(s *Service) (*container.Config, *container.HostConfig, error) {
+	args, envs, err := d.applyTemplate(s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to apply template, err: %w", err)
+	}
+
+	volumes, err := d.containerVolumes(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	binds := []string{}
+	for _, hostPath := range sortedKeys(volumes) {
+		binds = append(binds, fmt.Sprintf("%s:%s", hostPath, volumes[hostPath]))
+	}
+
+	env := []string{}
+	for _, key := range sortedKeys(envs) {
+		env = append(env, fmt.Sprintf("%s=%s", key, envs[key]))
+	}
+
+	config := &container.Config{
+		Image:        fmt.Sprintf("%s:%s", s.Image, s.Tag),
+		Cmd:          args,
+		Env:          env,
+		Labels:       d.containerLabels(s),
+		ExposedPorts: nat.PortSet{},
+	}
+	if s.Entrypoint != "" {
+		config.Entrypoint = []string{s.Entrypoint}
+	}
+	if s.ReadyCheck != nil {
+		config.Healthcheck = &container.HealthConfig{
+			Test:        healthcheckTest(s.ReadyCheck),
+			Interval:    s.ReadyCheck.Interval,
+			Timeout:     s.ReadyCheck.Timeout,
+			StartPeriod: s.ReadyCheck.StartPeriod,
+			Retries:     s.ReadyCheck.Retries,
+		}
+	}
+
+	hostConfig := &container.HostConfig{
+		Binds:        binds,
+		Privileged:   s.Privileged,
+		PortBindings: nat.PortMap{},
+	}
+	if runtime.GOOS == "linux" || d.runtime == RuntimePodman {
+		// host.docker.internal is only available on Macos and Windows (see toDockerComposeService)
+		hostConfig.ExtraHosts = []string{"host.docker.internal:" + d.runtime.hostGateway()}
+	}
+
+	for _, p := range s.Ports {
+		protocol := p.Protocol
+		if protocol == "" {
+			protocol = ProtocolTCP
+		}
+		port, err := nat.NewPort(protocol, strconv.Itoa(p.Port))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid port %s: %w", p.Name, err)
+		}
+		config.ExposedPorts[port] = struct{}{}
+
+		if d.exposePorts(s) {
+			binding := nat.PortBinding{HostPort: strconv.Itoa(p.HostPort)}
+			if d.bindHostPortsLocally {
+				binding.HostIP = "127.0.0.1"
+			}
+			hostConfig.PortBindings[port] = append(hostConfig.PortBindings[port], binding)
+		}
+	}
+
+	return config, hostConfig, nil
+}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+)
+
+// explorerVariant returns the Blockscout json rpc variant of the service if it is the
+// execution layer of a chain (the L1 nodes, the op-geth of the L2 chains and op-talos)
+func explorerVariant(svc *Service) (string, bool) {
+	switch svc.component.(type) {
+	case *NethermindEL:
+		return "nethermind", true
+	case *RethEL, *GethEL, *OpGeth, *OpTalos:
+		return "geth", true
+	}
+	return "", false
+}
+
+// CreateExplorerServices adds a Blockscout explorer (and its database) for each execution
+// layer in the manifest (i.e. el, el-1, op-geth, op-geth-1). It returns the names of the
+// explorer services. If behindCaddy is set, the explorer serves its pages under the
+// /<name>/http path of the Caddy routes.
+func CreateExplorerServices(manifest *Manifest, behindCaddy bool) ([]string, error) {
+	explorers := []string{}
+	for _, svc := range manifest.Services() {
+		variant, ok := explorerVariant(svc)
+		if !ok {
+			continue
+		}
+
+		target := svc.Name
+		name, dbName := target+"-explorer", target+"-explorer-db"
+		manifest.AddService(dbName, &BlockscoutPostgres{})
+
+		explorer := &Blockscout{
+			ELNode:   target,
+			Postgres: dbName,
+			Variant:  variant,
+		}
+		if behindCaddy {
+			explorer.NetworkPath = fmt.Sprintf("/%s/http", name)
+		}
+		manifest.AddService(name, explorer)
+		explorers = append(explorers, name)
+	}
+
+	if len(explorers) == 0 {
+		return nil, fmt.Errorf("no execution layer to explore in the manifest")
+	}
+	return explorers, nil
+}
+
+// BlockscoutPostgres is the database of a Blockscout explorer
+type BlockscoutPostgres struct{}
+
+func (b *BlockscoutPostgres) Run(service *Service, ctx *ExContext) {
+	service.
+		WithImage("postgres").
+		WithTag("15").
+		WithPort("postgres", 5432).
+		WithEnv("POSTGRES_USER", "postgres").
+		WithEnv("POSTGRES_PASSWORD", "postgres").
+		WithEnv("POSTGRES_DB", "blockscout").
+		WithReady(ReadyCheck{
+			Test:        []string{"CMD-SHELL", "pg_isready -U postgres -d blockscout"},
+			Interval:    1 * time.Second,
+			Timeout:     30 * time.Second,
+			Retries:     3,
+			StartPeriod: 1 * time.Second,
+		})
+}
+
+func (b *BlockscoutPostgres) Name() string {
+	return "blockscout-postgres"
+}
+
+// Blockscout is the Blockscout explorer (indexer and web app) of an execution layer
+type Blockscout struct {
+	ELNode   string
+	Postgres string
+
+	// Variant is the Blockscout json rpc variant of the el node (geth or nethermind)
+	Variant string
+
+	// NetworkPath is the path prefix of the pages if the explorer is behind a proxy
+	NetworkPath string
+}
+
+// blockscoutSecretKeyBase is the key used by the Blockscout web app to sign the sessions
+const blockscoutSecretKeyBase = "56NtB48ear7+wMSf0IQuWDAAazhpb31qyc7GiyspBP2vh7t5zlCsF5QDv76chXeN"
+
+func (b *Blockscout) Run(service *Service, ctx *ExContext) {
+	service.
+		WithImage("blockscout/blockscout").
+		WithTag("5.4.0").
+		WithEntrypoint("/bin/sh").
+		WithArgs(
+			"-c",
+			`bin/blockscout eval "Elixir.Explorer.ReleaseTasks.create_and_migrate()" && bin/blockscout start`,
+		).
+		WithEnv("PORT", `{{Port "http" 4000}}`).
+		WithEnv("DATABASE_URL", "postgresql://postgres:postgres@"+ConnectRaw(b.Postgres, "postgres", "")+"/blockscout").
+		WithEnv("ECTO_USE_SSL", "false").
+		WithEnv("SECRET_KEY_BASE", blockscoutSecretKeyBase).
+		WithEnv("ETHEREUM_JSONRPC_VARIANT", b.Variant).
+		WithEnv("ETHEREUM_JSONRPC_HTTP_URL", Connect(b.ELNode, "http")).
+		WithEnv("ETHEREUM_JSONRPC_TRACE_URL", Connect(b.ELNode, "http")).
+		WithEnv("COIN", "ETH").
+		WithEnv("SUBNETWORK", b.ELNode).
+		WithEnv("DISABLE_EXCHANGE_RATES", "true").
+		WithEnv("INDEXER_DISABLE_PENDING_TRANSACTIONS_FETCHER", "true").
+		WithEnv("BLOCK_TRANSFORMER", "base").
+		DependsOnHealthy(b.Postgres).
+		DependsOnRunning(b.ELNode)
+
+	if b.NetworkPath != "" {
+		service.WithEnv("NETWORK_PATH", b.NetworkPath)
+	}
+}
+
+func (b *Blockscout) Name() string {
+	return "blockscout"
+}
@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCreateExplorerServices(t *testing.T) {
+	cases := []struct {
+		manifest  *Manifest
+		explorers []string
+	}{
+		{applyL1Recipe(t, "--l1-nodes", "2"), []string{"el-explorer", "el-1-explorer"}},
+		{applyL1Recipe(t, "--el-client", "nethermind"), []string{"el-explorer"}},
+	}
+	_, opManifest := applyOpRecipe(t, "--l2-chains", "2", "--op-deployer", "op-deployer", "--external-builder", "op-reth")
+	cases = append(cases, struct {
+		manifest  *Manifest
+		explorers []string
+	}{opManifest, []string{"el-explorer", "op-geth-explorer", "op-geth-1-explorer"}})
+
+	for _, c := range cases {
+		explorers, err := CreateExplorerServices(c.manifest, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the builders (i.e. op-reth) are not explored, they follow the chain of op-geth
+		if !slices.Equal(explorers, c.explorers) {
+			t.Fatalf("expected the explorers %v, got %v", c.explorers, explorers)
+		}
+		for _, name := range explorers {
+			explorer := c.manifest.MustGetService(name).component.(*Blockscout)
+			elService := c.manifest.MustGetService(explorer.ELNode)
+			if _, ok := elService.component.(*NethermindEL); ok != (explorer.Variant == "nethermind") {
+				t.Fatalf("unexpected variant %s of %s", explorer.Variant, name)
+			}
+			if _, ok := c.manifest.GetService(explorer.Postgres); !ok {
+				t.Fatalf("expected the database of %s", name)
+			}
+		}
+	}
+
+	if _, err := CreateExplorerServices(NewManifest(&ExContext{}, &output{dst: t.TempDir()}), false); err == nil {
+		t.Fatal("expected an error without execution layer")
+	}
+}
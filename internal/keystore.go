@@ -0,0 +1,217 @@
+package internal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/prysmaticlabs/prysm/v5/crypto/bls/common"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Same parameters as the default (pbkdf2) cipher of the keystorev4 encryptor
+const (
+	keystorePbkdf2KeyLen = 32
+	keystorePbkdf2C      = 262144
+	keystorePbkdf2PRF    = "hmac-sha256"
+)
+
+// seededBytes derives a deterministic value for the given seed, public key and label
+func seededBytes(seed string, pubKey []byte, label string) []byte {
+	h := sha256.New()
+	h.Write([]byte(seed))
+	h.Write(pubKey)
+	h.Write([]byte(label))
+	return h.Sum(nil)
+}
+
+// deterministicUUID returns a (version 4 formatted) uuid derived from the seed and the public key
+func deterministicUUID(seed string, pubKey []byte) (string, error) {
+	buf := seededBytes(seed, pubKey, "uuid")[:16]
+	buf[6] = (buf[6] & 0x0f) | 0x40
+	buf[8] = (buf[8] & 0x3f) | 0x80
+	return uuid.FormatUUID(buf)
+}
+
+// encryptKeystoreDeterministic encrypts the secret with the EIP-2335 (keystore v4) format
+// like keystorev4.Encrypt but with the salt and the iv derived from the seed and the public key
+// instead of random values. The output is byte-identical across runs.
+func encryptKeystoreDeterministic(secret []byte, passphrase string, seed string, pubKey []byte) (map[string]interface{}, error) {
+	salt := seededBytes(seed, pubKey, "salt")
+	iv := seededBytes(seed, pubKey, "iv")[:16]
+
+	decryptionKey := pbkdf2.Key([]byte(passphrase), salt, keystorePbkdf2C, keystorePbkdf2KeyLen, sha256.New)
+
+	aesCipher, err := aes.NewCipher(decryptionKey[:16])
+	if err != nil {
+		return nil, err
+	}
+	cipherMsg := make([]byte, len(secret))
+	cipher.NewCTR(aesCipher, iv).XORKeyStream(cipherMsg, secret)
+
+	h := sha256.New()
+	h.Write(decryptionKey[16:32])
+	h.Write(cipherMsg)
+	checksumMsg := h.Sum(nil)
+
+	obj := map[string]interface{}{
+		"kdf": map[string]interface{}{
+			"function": "pbkdf2",
+			"params": map[string]interface{}{
+				"dklen": keystorePbkdf2KeyLen,
+				"c":     keystorePbkdf2C,
+				"prf":   keystorePbkdf2PRF,
+				"salt":  hex.EncodeToString(salt),
+			},
+			"message": "",
+		},
+		"checksum": map[string]interface{}{
+			"function": "sha256",
+			"params":   map[string]interface{}{},
+			"message":  hex.EncodeToString(checksumMsg),
+		},
+		"cipher": map[string]interface{}{
+			"function": "aes-128-ctr",
+			"params": map[string]interface{}{
+				"iv": hex.EncodeToString(iv),
+			},
+			"message": hex.EncodeToString(cipherMsg),
+		},
+	}
+
+	// go to JSON and back to return the same generic map as keystorev4
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// encryptKeystore encrypts the secret with the keystore v4 format and returns the crypto fields and
+// the uuid of the keystore. If the seed is set, the output is deterministic (see encryptKeystoreDeterministic)
+// and the label (i.e. the public key) is used to derive the salt, the iv and the uuid.
+func encryptKeystore(secretMsg []byte, passphrase string, seed string, label []byte) (map[string]interface{}, string, error) {
+	if seed == "" {
+		cryptoFields, err := keystorev4.New().Encrypt(secretMsg, passphrase)
+		if err != nil {
+			return nil, "", err
+		}
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, "", err
+		}
+		return cryptoFields, id, nil
+	}
+
+	cryptoFields, err := encryptKeystoreDeterministic(secretMsg, passphrase, seed, label)
+	if err != nil {
+		return nil, "", err
+	}
+	id, err := deterministicUUID(seed, label)
+	if err != nil {
+		return nil, "", err
+	}
+	return cryptoFields, id, nil
+}
+
+// encodeValidatorKeystore returns the EIP-2335 keystore of the validator key encrypted with the default secret
+func encodeValidatorKeystore(key common.SecretKey, seed string) ([]byte, error) {
+	pubKey := key.PublicKey().Marshal()
+	cryptoFields, id, err := encryptKeystore(key.Marshal(), secret, seed, pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	item := map[string]interface{}{
+		"crypto":      cryptoFields,
+		"uuid":        id,
+		"pubkey":      hex.EncodeToString(pubKey), // without 0x in the json file
+		"version":     4,
+		"description": "",
+	}
+	return json.MarshalIndent(item, "", "\t")
+}
+
+// tekuKeystore writes the validator keys in the layout of the teku '--validator-keys' flag.
+// Each keystore in 'keys' has a password file with the same name in 'secrets'.
+type tekuKeystore struct {
+	privKeys []common.SecretKey
+
+	// seed is used to derive the salt, the iv and the uuid of the keystores.
+	// If it is empty, they are random.
+	seed string
+}
+
+func (t *tekuKeystore) Encode(o *output) error {
+	for _, key := range t.privKeys {
+		pubKeyHex := "0x" + hex.EncodeToString(key.PublicKey().Marshal())
+		valJSON, err := encodeValidatorKeystore(key, t.seed)
+		if err != nil {
+			return err
+		}
+
+		if err := o.WriteBatch(map[string]interface{}{
+			"keys/" + pubKeyHex + ".json":   valJSON,
+			"secrets/" + pubKeyHex + ".txt": secret,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prysmKeystore writes the validator keys as a Prysm wallet with the local (direct) keymanager.
+// All the keys are stored in a single keystore encrypted with the wallet password.
+type prysmKeystore struct {
+	privKeys []common.SecretKey
+
+	// seed is used to derive the salt, the iv and the uuid of the keystore.
+	// If it is empty, they are random.
+	seed string
+}
+
+func (p *prysmKeystore) Encode(o *output) error {
+	// same format as the accountStore of the prysm local keymanager
+	store := struct {
+		PrivateKeys [][]byte `json:"private_keys"`
+		PublicKeys  [][]byte `json:"public_keys"`
+	}{
+		PrivateKeys: [][]byte{},
+		PublicKeys:  [][]byte{},
+	}
+	for _, key := range p.privKeys {
+		store.PrivateKeys = append(store.PrivateKeys, key.Marshal())
+		store.PublicKeys = append(store.PublicKeys, key.PublicKey().Marshal())
+	}
+	encodedStore, err := json.MarshalIndent(store, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	cryptoFields, id, err := encryptKeystore(encodedStore, secret, p.seed, []byte("all-accounts"))
+	if err != nil {
+		return err
+	}
+	accounts, err := json.MarshalIndent(map[string]interface{}{
+		"crypto":  cryptoFields,
+		"uuid":    id,
+		"version": 4,
+		"name":    "keystore",
+	}, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return o.WriteBatch(map[string]interface{}{
+		"wallet/direct/accounts/all-accounts.keystore.json": accounts,
+		"wallet-password.txt":                               secret,
+	})
+}
@@ -0,0 +1,692 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// kubernetesManifestFile is the name of the file in the output folder with the rendered resources
+	kubernetesManifestFile = "kubernetes.yaml"
+
+	// kubernetesVolumeSize is the storage requested for each named volume of a service
+	kubernetesVolumeSize = "10Gi"
+
+	// kubernetesMaxDataSize is the size limit of the data of a ConfigMap or a Secret
+	kubernetesMaxDataSize = 1024 * 1024
+)
+
+// secretArtifacts are the artifacts that contain private keys and that are mounted
+// as a Secret instead of a ConfigMap
+var secretArtifacts = []string{
+	"jwtsecret",
+	"data_validator",
+	"deterministic_p2p_key.txt",
+}
+
+// KubernetesRunner deploys the services of the manifest on a Kubernetes cluster.
+// Each service is converted into a Deployment (or a StatefulSet if it uses named volumes),
+// a Service for the ports it exposes and a ConfigMap/Secret with the artifacts it mounts.
+type KubernetesRunner struct {
+	out      *output
+	manifest *Manifest
+
+	// namespace is the namespace where the resources are deployed. It must exist.
+	namespace string
+
+	// renderOnly only writes the resources to the output folder without applying them
+	renderOnly bool
+
+	sessionID string
+	labels    map[string]string
+
+	exitErr chan error
+}
+
+func NewKubernetesRunner(out *output, manifest *Manifest, namespace string, renderOnly bool, labels map[string]string) (*KubernetesRunner, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+	names := map[string]string{}
+	for _, svc := range manifest.services {
+		if svc.Labels[useHostExecutionLabel] == "true" {
+			return nil, fmt.Errorf("service '%s' runs on the host and cannot be deployed on Kubernetes", svc.Name)
+		}
+		if readyHookRequired(svc.component) {
+			return nil, fmt.Errorf("service '%s' has a ready hook that queries it from the host and cannot be deployed on Kubernetes", svc.Name)
+		}
+		name := kubernetesName(svc.Name)
+		if name == "" {
+			return nil, fmt.Errorf("service '%s' has no valid Kubernetes name", svc.Name)
+		}
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("services '%s' and '%s' have the same Kubernetes name '%s'", other, svc.Name, name)
+		}
+		names[name] = svc.Name
+		for _, volumeName := range svc.VolumesMapped {
+			if _, _, ok := splitSharedVolume(volumeName); ok {
+				return nil, fmt.Errorf("service '%s' shares a volume with another service and cannot be deployed on Kubernetes", svc.Name)
+			}
+		}
+	}
+	if len(manifest.overrides) != 0 {
+		return nil, fmt.Errorf("host overrides are not supported on Kubernetes")
+	}
+
+	k := &KubernetesRunner{
+		out:        out,
+		manifest:   manifest,
+		namespace:  namespace,
+		renderOnly: renderOnly,
+		sessionID:  uuid.New().String(),
+		labels:     labels,
+		exitErr:    make(chan error, 1),
+	}
+	return k, nil
+}
+
+// Instances returns nil since the ready hooks and the watchdogs expect the services to
+// be reachable from the host machine which is not the case inside the cluster. The services
+// with a required ready hook are rejected by NewKubernetesRunner and --watchdog by the CLI.
+func (k *KubernetesRunner) Instances() []*instance {
+	return nil
+}
+
+// readyHookRequired returns whether the ready hook of the component has to run for the
+// deployment to work (i.e. the enode of a node that the other nodes peer with). The other
+// hooks only fill the output of the recipe (i.e. the enode of op-geth) and are skipped.
+func readyHookRequired(component ServiceGen) bool {
+	switch c := component.(type) {
+	case *RethEL:
+		return c.Bootnode || c.TrustedPeer != ""
+	case *GethEL:
+		return c.Bootnode
+	case *LighthouseBeaconNode:
+		return c.Bootnode != "" || c.TargetPeers != 0
+	}
+	return false
+}
+
+func (k *KubernetesRunner) ExitErr() <-chan error {
+	return k.exitErr
+}
+
+func (k *KubernetesRunner) Run() error {
+	data, err := k.Render()
+	if err != nil {
+		return fmt.Errorf("failed to render kubernetes manifest: %w", err)
+	}
+	if err := k.out.WriteFile(kubernetesManifestFile, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", kubernetesManifestFile, err)
+	}
+	if k.renderOnly {
+		return nil
+	}
+	return k.kubectl(context.Background(), "apply", "-f", filepath.Join(k.out.dst, kubernetesManifestFile))
+}
+
+func (k *KubernetesRunner) Stop() error {
+	if k.renderOnly {
+		return nil
+	}
+	// only remove the resources that belong to this session
+	return k.kubectl(context.Background(), "delete", "deployments,statefulsets,services,configmaps,secrets,persistentvolumeclaims",
+		"-l", "playground.session="+k.sessionID, "--ignore-not-found")
+}
+
+func (k *KubernetesRunner) WaitForReady(ctx context.Context, timeout time.Duration) error {
+	if k.renderOnly {
+		return nil
+	}
+	for {
+		// 'kubectl wait' fails right away if the pods are not created yet, retry until they are.
+		err := k.kubectl(ctx, "wait", "--for=condition=Ready", "pods", "-l", "playground.session="+k.sessionID, "--timeout="+timeout.String())
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(1 * time.Second):
+		}
+	}
+}
+
+func (k *KubernetesRunner) kubectl(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, "kubectl", append([]string{"--namespace", k.namespace}, args...)...)
+
+	var errOut bytes.Buffer
+	cmd.Stderr = &errOut
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run kubectl %s: %w, err: %s", args[0], err, errOut.String())
+	}
+	return nil
+}
+
+// Render returns the Kubernetes resources for all the services in the manifest
+// as a multi-document YAML file.
+func (k *KubernetesRunner) Render() ([]byte, error) {
+	var buf bytes.Buffer
+	for _, svc := range k.manifest.services {
+		resources, err := k.toKubernetesResources(svc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert service %s to kubernetes resources: %w", svc.Name, err)
+		}
+		for _, resource := range resources {
+			data, err := yaml.Marshal(resource)
+			if err != nil {
+				return nil, err
+			}
+			buf.WriteString("---\n")
+			buf.Write(data)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// applyTemplate resolves the templates from the manifest into the actual values
+// inside the cluster. The services are reachable with the DNS name of their Kubernetes Service
+// and the ports are the same as the ones in the container.
+func (k *KubernetesRunner) applyTemplate(s *Service) ([]string, map[string]string, error) {
+	funcs := template.FuncMap{
+		"Service": func(name string, portLabel, protocol string) string {
+			protocolPrefix := ""
+			if protocol == "http" {
+				protocolPrefix = "http://"
+			}
+
+			svc := k.manifest.MustGetService(name)
+			port := svc.MustGetPort(portLabel)
+			return fmt.Sprintf("%s%s:%d", protocolPrefix, kubernetesName(svc.Name), port.Port)
+		},
+		"Port": func(name string, defaultPort int) int {
+			return defaultPort
+		},
+		"PortUDP": func(name string, defaultPort int) int {
+			return defaultPort
+		},
+	}
+
+	return resolveServiceTemplates(s, funcs)
+}
+
+func (k *KubernetesRunner) objectMeta(name string, labels map[string]string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":      name,
+		"namespace": k.namespace,
+		"labels":    labels,
+	}
+}
+
+func (k *KubernetesRunner) toKubernetesResources(s *Service) ([]interface{}, error) {
+	args, envs, err := k.applyTemplate(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply template, err: %w", err)
+	}
+
+	// the names of the resources and the DNS names of the services are DNS-1123 labels
+	name := kubernetesName(s.Name)
+
+	labels := map[string]string{
+		// It is important to use the playground label to identify the resources
+		// during the cleanup process
+		"playground":         "true",
+		"playground.session": k.sessionID,
+		"service":            name,
+	}
+
+	// apply the user defined labels
+	for key, value := range k.labels {
+		labels[key] = value
+	}
+
+	resources := []interface{}{}
+
+	container := map[string]interface{}{
+		"name":  name,
+		"image": fmt.Sprintf("%s:%s", s.Image, s.Tag),
+	}
+	if s.Entrypoint != "" {
+		container["command"] = []string{s.Entrypoint}
+	}
+	if len(args) > 0 {
+		container["args"] = args
+	}
+
+	if len(envs) > 0 {
+		// sort the variables to have a stable output
+		env := []map[string]string{}
+		for _, key := range sortedKeys(envs) {
+			env = append(env, map[string]string{"name": key, "value": envs[key]})
+		}
+		container["env"] = env
+	}
+
+	if s.Privileged {
+		container["securityContext"] = map[string]interface{}{
+			"privileged": true,
+		}
+	}
+
+	if s.ReadyCheck != nil {
+		probe, err := toReadinessProbe(s.ReadyCheck)
+		if err != nil {
+			return nil, err
+		}
+		container["readinessProbe"] = probe
+	}
+
+	// Ports
+	if len(s.Ports) > 0 {
+		containerPorts := []map[string]interface{}{}
+		servicePorts := []map[string]interface{}{}
+		portNames := map[string]string{}
+		for _, p := range s.Ports {
+			name := kubernetesPortName(p)
+			if other, ok := portNames[name]; ok {
+				return nil, fmt.Errorf("the ports %s and %s of service %s have the same kubernetes name %s", other, p.Name, s.Name, name)
+			}
+			portNames[name] = p.Name
+			protocol := strings.ToUpper(p.Protocol)
+			if protocol == "" {
+				protocol = "TCP"
+			}
+			containerPorts = append(containerPorts, map[string]interface{}{
+				"name":          name,
+				"containerPort": p.Port,
+				"protocol":      protocol,
+			})
+			servicePorts = append(servicePorts, map[string]interface{}{
+				"name":       name,
+				"port":       p.Port,
+				"targetPort": p.Port,
+				"protocol":   protocol,
+			})
+		}
+		container["ports"] = containerPorts
+
+		resources = append(resources, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   k.objectMeta(name, labels),
+			"spec": map[string]interface{}{
+				"selector": map[string]string{
+					"playground.session": k.sessionID,
+					"service":            name,
+				},
+				"ports": servicePorts,
+			},
+		})
+	}
+
+	// Artifacts. Each file mapped is a volume from the ConfigMap (or the Secret) of the service
+	// with the files of the artifact. Single files are mounted with a subPath.
+	configData := map[string]string{}
+	configBinaryData := map[string]string{}
+	secretData := map[string]string{}
+
+	volumes := []map[string]interface{}{}
+	volumeMounts := []map[string]interface{}{}
+
+	configMapName := name + "-artifacts"
+	secretName := name + "-secrets"
+
+	// keySources is the artifact file of each key, the same file can be mapped more than once
+	keySources := map[string]string{}
+
+	for i, containerPath := range sortedKeys(s.FilesMapped) {
+		artifactName := s.FilesMapped[containerPath]
+		isSecret := isSecretArtifact(artifactName)
+
+		files, isDir, err := k.readArtifact(artifactName)
+		if err != nil {
+			return nil, err
+		}
+
+		items := []map[string]string{}
+		for _, path := range sortedKeys(files) {
+			content := files[path]
+
+			source := artifactName
+			itemPath := filepath.Base(containerPath)
+			if isDir {
+				source = filepath.Join(artifactName, path)
+				itemPath = path
+			}
+			key := kubernetesDataKey(source)
+			if other, ok := keySources[key]; ok && other != source {
+				return nil, fmt.Errorf("the artifacts %s and %s of service %s have the same kubernetes key %s", other, source, s.Name, key)
+			}
+			keySources[key] = source
+
+			if isSecret {
+				secretData[key] = base64.StdEncoding.EncodeToString(content)
+			} else if utf8.Valid(content) {
+				configData[key] = string(content)
+			} else {
+				configBinaryData[key] = base64.StdEncoding.EncodeToString(content)
+			}
+
+			items = append(items, map[string]string{"key": key, "path": itemPath})
+		}
+
+		volumeName := fmt.Sprintf("artifact-%d", i)
+		volume := map[string]interface{}{
+			"name": volumeName,
+		}
+		if isSecret {
+			volume["secret"] = map[string]interface{}{"secretName": secretName, "items": items}
+		} else {
+			volume["configMap"] = map[string]interface{}{"name": configMapName, "items": items}
+		}
+		volumes = append(volumes, volume)
+
+		mount := map[string]interface{}{
+			"name":      volumeName,
+			"mountPath": containerPath,
+			"readOnly":  true,
+		}
+		if !isDir {
+			mount["subPath"] = filepath.Base(containerPath)
+		}
+		volumeMounts = append(volumeMounts, mount)
+	}
+
+	// the artifacts are stored in etcd, check the limit before the resources are applied
+	if size := kubernetesDataSize(configData) + kubernetesDataSize(configBinaryData); size > kubernetesMaxDataSize {
+		return nil, fmt.Errorf("the artifacts of service %s are %d bytes, more than the %d bytes of a ConfigMap", s.Name, size, kubernetesMaxDataSize)
+	}
+	if size := kubernetesDataSize(secretData); size > kubernetesMaxDataSize {
+		return nil, fmt.Errorf("the secret artifacts of service %s are %d bytes, more than the %d bytes of a Secret", s.Name, size, kubernetesMaxDataSize)
+	}
+
+	if len(configData) > 0 || len(configBinaryData) > 0 {
+		configMap := map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   k.objectMeta(configMapName, labels),
+		}
+		if len(configData) > 0 {
+			configMap["data"] = configData
+		}
+		if len(configBinaryData) > 0 {
+			configMap["binaryData"] = configBinaryData
+		}
+		resources = append(resources, configMap)
+	}
+	if len(secretData) > 0 {
+		resources = append(resources, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   k.objectMeta(secretName, labels),
+			"type":       "Opaque",
+			"data":       secretData,
+		})
+	}
+
+	// Volumes. Absolute paths are mounted from the host and named volumes
+	// are persistent volume claims of a StatefulSet.
+	claims := []map[string]interface{}{}
+	for _, containerPath := range sortedKeys(s.VolumesMapped) {
+		volumeName := s.VolumesMapped[containerPath]
+		if filepath.IsAbs(volumeName) {
+			name := fmt.Sprintf("host-%d", len(volumes))
+			volumes = append(volumes, map[string]interface{}{
+				"name":     name,
+				"hostPath": map[string]string{"path": volumeName},
+			})
+			volumeMounts = append(volumeMounts, map[string]interface{}{
+				"name":      name,
+				"mountPath": containerPath,
+			})
+			continue
+		}
+
+		name := kubernetesName(volumeName)
+		claims = append(claims, map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":   name,
+				"labels": labels,
+			},
+			"spec": map[string]interface{}{
+				"accessModes": []string{"ReadWriteOnce"},
+				"resources": map[string]interface{}{
+					"requests": map[string]string{"storage": kubernetesVolumeSize},
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, map[string]interface{}{
+			"name":      name,
+			"mountPath": containerPath,
+		})
+	}
+
+	if len(volumeMounts) > 0 {
+		container["volumeMounts"] = volumeMounts
+	}
+
+	podSpec := map[string]interface{}{
+		"containers": []interface{}{container},
+	}
+	if len(volumes) > 0 {
+		podSpec["volumes"] = volumes
+	}
+
+	podMeta := map[string]interface{}{
+		"labels": labels,
+	}
+	if len(s.DependsOn) > 0 {
+		// Kubernetes does not have an equivalent for depends_on. The services are expected
+		// to retry until their dependencies are available, keep the information as an annotation.
+		deps := []string{}
+		for _, dep := range s.DependsOn {
+			deps = append(deps, kubernetesName(dep.Name))
+		}
+		podMeta["annotations"] = map[string]string{
+			"playground/depends-on": strings.Join(deps, ","),
+		}
+	}
+
+	spec := map[string]interface{}{
+		"replicas": 1,
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]string{
+				"playground.session": k.sessionID,
+				"service":            name,
+			},
+		},
+		"template": map[string]interface{}{
+			"metadata": podMeta,
+			"spec":     podSpec,
+		},
+	}
+
+	kind := "Deployment"
+	if len(claims) > 0 {
+		kind = "StatefulSet"
+		spec["serviceName"] = name
+		spec["volumeClaimTemplates"] = claims
+	} else {
+		spec["strategy"] = map[string]string{"type": "Recreate"}
+	}
+
+	resources = append(resources, map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       kind,
+		"metadata":   k.objectMeta(name, labels),
+		"spec":       spec,
+	})
+
+	return resources, nil
+}
+
+// readArtifact reads the artifact from the output folder. If the artifact is a directory,
+// it returns all the files inside it indexed by their relative path.
+func (k *KubernetesRunner) readArtifact(name string) (map[string][]byte, bool, error) {
+	path := filepath.Join(k.out.dst, name)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to stat artifact %s: %w", name, err)
+	}
+	if !info.IsDir() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read artifact %s: %w", name, err)
+		}
+		return map[string][]byte{filepath.Base(name): content}, false, nil
+	}
+
+	files := map[string][]byte{}
+	err = filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(path, filePath)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		files[rel] = content
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read artifact %s: %w", name, err)
+	}
+	return files, true, nil
+}
+
+func toReadinessProbe(check *ReadyCheck) (map[string]interface{}, error) {
+	probe := map[string]interface{}{
+		"periodSeconds":       durationSeconds(check.Interval),
+		"timeoutSeconds":      durationSeconds(check.Timeout),
+		"initialDelaySeconds": int(check.StartPeriod.Seconds()),
+	}
+	if check.Retries > 0 {
+		probe["failureThreshold"] = check.Retries
+	}
+
+	if check.QueryURL != "" {
+		u, err := url.Parse(check.QueryURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ready check url %s: %w", check.QueryURL, err)
+		}
+		port, err := strconv.Atoi(u.Port())
+		if err != nil {
+			return nil, fmt.Errorf("ready check url %s does not have a port", check.QueryURL)
+		}
+		probe["httpGet"] = map[string]interface{}{
+			"path": u.RequestURI(),
+			"port": port,
+		}
+		return probe, nil
+	}
+
+	if len(check.Test) < 2 {
+		return nil, fmt.Errorf("invalid ready check test %v", check.Test)
+	}
+	var command []string
+	switch check.Test[0] {
+	case "CMD-SHELL":
+		command = []string{"sh", "-c", strings.Join(check.Test[1:], " ")}
+	case "CMD":
+		command = check.Test[1:]
+	default:
+		return nil, fmt.Errorf("unsupported ready check test type %s", check.Test[0])
+	}
+	probe["exec"] = map[string]interface{}{
+		"command": command,
+	}
+	return probe, nil
+}
+
+// durationSeconds converts the duration into seconds for the probes, which require at least one second
+func durationSeconds(d time.Duration) int {
+	if d < time.Second {
+		return 1
+	}
+	return int(d.Seconds())
+}
+
+func isSecretArtifact(name string) bool {
+	for _, secret := range secretArtifacts {
+		// the validator keys might be split in several 'data_validator_<i>' artifacts
+		if name == secret || strings.HasPrefix(name, secret+"/") || strings.HasPrefix(name, secret+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+var invalidKubernetesNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// kubernetesName converts the name into a valid DNS-1123 label (i.e. op_geth to op-geth)
+func kubernetesName(name string) string {
+	name = strings.Trim(invalidKubernetesNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(name) > 63 {
+		name = strings.Trim(name[:63], "-")
+	}
+	return name
+}
+
+// kubernetesPortName returns a valid and unique name for the port. Port names
+// are at most 15 characters and a TCP and a UDP port can share the same name in the manifest.
+func kubernetesPortName(p *Port) string {
+	name := kubernetesName(p.Name)
+	if p.Protocol == ProtocolUDP {
+		name += "-udp"
+	}
+	if len(name) > 15 {
+		name = strings.Trim(name[:15], "-")
+	}
+	return name
+}
+
+var invalidKubernetesKeyChars = regexp.MustCompile(`[^-._a-zA-Z0-9]+`)
+
+// kubernetesDataKey converts the path of an artifact into a valid ConfigMap/Secret key
+func kubernetesDataKey(path string) string {
+	return invalidKubernetesKeyChars.ReplaceAllString(filepath.ToSlash(path), ".")
+}
+
+// kubernetesDataSize returns the size of the data of a ConfigMap or a Secret
+func kubernetesDataSize(data map[string]string) int {
+	size := 0
+	for key, value := range data {
+		size += len(key) + len(value)
+	}
+	return size
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,228 @@
+package internal
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+// kubernetesTestComponent is a component with all the features supported by the kubernetes runner
+type kubernetesTestComponent struct{}
+
+func (k *kubernetesTestComponent) Run(service *Service, ctx *ExContext) {
+	service.
+		WithImage("ghcr.io/paradigmxyz/reth").
+		WithTag("v1.3.1").
+		WithEntrypoint("/usr/local/bin/reth").
+		WithArgs(
+			"node",
+			"--http.port", `{{Port "http" 8545}}`,
+			"--port", `{{Port "rpc" 30303}}`,
+			"--discovery.port", `{{PortUDP "rpc" 30303}}`,
+			"--authrpc.jwtsecret", "/data/jwtsecret",
+			"--datadir", "/data_reth",
+		).
+		WithEnv("RUST_LOG", "info").
+		WithArtifact("/data/jwtsecret", "jwtsecret").
+		WithArtifact("/data/genesis.json", "genesis.json").
+		WithArtifact("/data/testnet-dir", "testnet").
+		WithVolume("data", "/data_reth").
+		WithReady(ReadyCheck{
+			QueryURL:    "http://localhost:8545/health",
+			Interval:    1 * time.Second,
+			Timeout:     10 * time.Second,
+			Retries:     3,
+			StartPeriod: 1 * time.Second,
+		})
+}
+
+func (k *kubernetesTestComponent) Name() string {
+	return "kubernetes-test"
+}
+
+type kubernetesTestClient struct{}
+
+func (k *kubernetesTestClient) Run(service *Service, ctx *ExContext) {
+	service.
+		WithImage("docker.io/library/busybox").
+		WithTag("latest").
+		WithArgs("--el", Connect("el", "http")).
+		WithEnv("EL_URL", ConnectRaw("el", "http", "ws")).
+		WithAbsoluteVolume("/var/run/docker.sock", "/var/run/docker.sock").
+		WithPrivileged().
+		WithReady(ReadyCheck{
+			Test:     []string{"CMD-SHELL", "wget -q -O - http://localhost:8080"},
+			Interval: 5 * time.Second,
+		}).
+		DependsOnHealthy("el")
+}
+
+func (k *kubernetesTestClient) Name() string {
+	return "kubernetes-test-client"
+}
+
+func TestKubernetesRunnerRender(t *testing.T) {
+	out := &output{dst: t.TempDir()}
+	artifacts := map[string]interface{}{
+		"jwtsecret":                "0x04592280e1778419b7aa954d43871cb2cfb2ebda754fb735e8adeb293a88f9bf",
+		"genesis.json":             `{"config": {"chainId": 1337}}`,
+		"testnet/config.yaml":      "PRESET_BASE: mainnet\n",
+		"testnet/genesis.ssz":      []byte{0x00, 0xff, 0xfe},
+		"testnet/deploy_block.txt": "0",
+	}
+	if err := out.WriteBatch(artifacts); err != nil {
+		t.Fatalf("failed to write artifacts: %v", err)
+	}
+
+	manifest := NewManifest(&ExContext{}, out)
+	manifest.AddService("el", &kubernetesTestComponent{})
+	manifest.AddService("client", &kubernetesTestClient{})
+	if err := manifest.Validate(); err != nil {
+		t.Fatalf("failed to validate manifest: %v", err)
+	}
+
+	runner, err := NewKubernetesRunner(out, manifest, "playground", true, map[string]string{"team": "builders"})
+	if err != nil {
+		t.Fatalf("failed to create kubernetes runner: %v", err)
+	}
+	// use a fixed session id to have a stable output
+	runner.sessionID = "test-session"
+
+	data, err := runner.Render()
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+
+	goldenFile := "./testcases/kubernetes_golden.yaml"
+	if *updateGolden {
+		if err := os.WriteFile(goldenFile, data, 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+
+	expected, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if string(data) != string(expected) {
+		t.Fatalf("rendered manifest does not match %s (run with -update to regenerate):\n%s", goldenFile, data)
+	}
+}
+
+func TestKubernetesRunnerNames(t *testing.T) {
+	out := &output{dst: t.TempDir()}
+	if err := out.WriteBatch(map[string]interface{}{
+		"jwtsecret":           "secret",
+		"genesis.json":        "{}",
+		"testnet/config.yaml": "PRESET_BASE: mainnet\n",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := NewManifest(&ExContext{}, out)
+	manifest.AddService("EL_1", &kubernetesTestComponent{})
+	manifest.AddService("client", &kubernetesTestClient{})
+	client := manifest.MustGetService("client")
+	client.Args, client.Env = []string{"--el", Connect("EL_1", "http")}, nil
+
+	runner, err := NewKubernetesRunner(out, manifest, "", true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources, err := runner.toKubernetesResources(manifest.MustGetService("EL_1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, resource := range resources {
+		if name := resource.(map[string]interface{})["metadata"].(map[string]interface{})["name"]; name != "el-1" && name != "el-1-artifacts" && name != "el-1-secrets" {
+			t.Fatalf("expected a DNS-1123 name, got %s", name)
+		}
+	}
+	// the services are reachable with the DNS name of their Kubernetes service
+	args, _, err := runner.applyTemplate(manifest.MustGetService("client"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args[1] != "http://el-1:8545" {
+		t.Fatalf("unexpected url of the el %s", args[1])
+	}
+
+	// two services cannot have the same kubernetes name
+	manifest.AddService("el-1", &kubernetesTestClient{})
+	if _, err := NewKubernetesRunner(out, manifest, "", true, nil); err == nil {
+		t.Fatal("expected an error for the services EL_1 and el-1")
+	}
+}
+
+func TestKubernetesRunnerReadyHooks(t *testing.T) {
+	out := &output{dst: t.TempDir()}
+
+	// the enode of op-geth is only part of the output
+	manifest := NewManifest(&ExContext{}, out)
+	manifest.AddService("op-geth", &OpGeth{})
+	manifest.AddService("el", &RethEL{})
+	if _, err := NewKubernetesRunner(out, manifest, "", true, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the nodes of a multi-node setup peer with the enode captured by the ready hook
+	manifest.AddService("el-1", &RethEL{TrustedPeer: "el"})
+	if _, err := NewKubernetesRunner(out, manifest, "", true, nil); err == nil {
+		t.Fatal("expected an error for the ready hook of el-1")
+	}
+}
+
+func TestKubernetesRunnerLimits(t *testing.T) {
+	out := &output{dst: t.TempDir()}
+	if err := out.WriteBatch(map[string]interface{}{
+		"a/b.json": "{}",
+		"a.b/json": "{}",
+		"large":    strings.Repeat("a", kubernetesMaxDataSize+1),
+		"small":    "a",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		apply func(svc *Service)
+		err   string
+	}{
+		{"ports", func(svc *Service) {
+			// both names are truncated to 15 characters
+			svc.WithPort("metrics-execution", 9090, ProtocolTCP).WithPort("metrics-executions", 9091, ProtocolTCP)
+		}, "same kubernetes name"},
+		{"keys", func(svc *Service) {
+			svc.WithArtifact("/data/b.json", "a/b.json").WithArtifact("/data/json", "a.b/json")
+		}, "same kubernetes key"},
+		{"size", func(svc *Service) {
+			svc.WithArtifact("/data/large", "large")
+		}, "bytes of a ConfigMap"},
+		{"valid", func(svc *Service) {
+			// the same artifact can be mounted twice
+			svc.WithArtifact("/data/small", "small").WithArtifact("/config/small", "small")
+		}, ""},
+	}
+	for _, c := range cases {
+		manifest := NewManifest(&ExContext{}, out)
+		svc := manifest.NewService(c.name).WithImage("docker.io/library/busybox").WithTag("latest")
+		c.apply(svc)
+		manifest.services = append(manifest.services, svc)
+
+		runner, err := NewKubernetesRunner(out, manifest, "", true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = runner.toKubernetesResources(svc)
+		if c.err == "" && err != nil {
+			t.Fatalf("unexpected error for %s: %v", c.name, err)
+		}
+		if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Fatalf("expected the error '%s' for %s, got %v", c.err, c.name, err)
+		}
+	}
+}
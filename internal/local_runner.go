@@ -19,6 +19,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/ethereum/go-ethereum/log"
@@ -39,6 +40,13 @@ type LocalRunner struct {
 	manifest *Manifest
 	client   *client.Client
 
+	// runtime is the container engine (docker or podman) behind the client
+	runtime ContainerRuntime
+
+	// minHostPort is the lowest port that can be published on the host. It is set
+	// for rootless runtimes which cannot bind privileged ports.
+	minHostPort int
+
 	// reservedPorts is a map of port numbers reserved for each service to avoid conflicts
 	// since we reserve ports for all the services before they are used
 	reservedPorts map[int]bool
@@ -72,9 +80,16 @@ type LocalRunner struct {
 	// it is used to identify the containers in the cleanup process
 	sessionID string
 
+	// sessionStartedAt is the start time of the session in the registry
+	sessionStartedAt time.Time
+
 	// networkName is the name of the network to use for the services
 	networkName string
 
+	// createdNetwork is set if the network is created by the playground, only then
+	// it is removed on stop
+	createdNetwork bool
+
 	// labels is the list of labels to apply to each resource being created
 	labels map[string]string
 }
@@ -106,11 +121,44 @@ func newDockerClient() (*client.Client, error) {
 	return client, nil
 }
 
-// TODO: add a runner config struct
-func NewLocalRunner(out *output, manifest *Manifest, overrides map[string]string, interactive bool, bindHostPortsLocally bool, networkName string, labels map[string]string) (*LocalRunner, error) {
-	client, err := newDockerClient()
+// LocalRunnerConfig is the configuration of the local runners
+type LocalRunnerConfig struct {
+	Out      *output
+	Manifest *Manifest
+
+	// Overrides is a map of service name to either a docker image or
+	// the path of the executable to run on the host machine.
+	Overrides map[string]string
+
+	Interactive          bool
+	BindHostPortsLocally bool
+	NetworkName          string
+	Labels               map[string]string
+
+	// Runtime is the container engine to use (docker or podman)
+	Runtime ContainerRuntime
+}
+
+func NewLocalRunner(cfg *LocalRunnerConfig) (*LocalRunner, error) {
+	out, manifest, overrides := cfg.Out, cfg.Manifest, cfg.Overrides
+
+	runtime := cfg.Runtime
+	if runtime == "" {
+		runtime = RuntimeDocker
+	}
+	client, err := newContainerClient(runtime)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create docker client: %w", err)
+		return nil, fmt.Errorf("failed to create %s client: %w", runtime, err)
+	}
+
+	// rootless runtimes cannot publish privileged ports on the host
+	minHostPort := 0
+	rootless, err := isRootless(client)
+	if err != nil {
+		return nil, err
+	}
+	if rootless {
+		minHostPort = unprivilegedPortStart()
 	}
 
 	// merge the overrides with the manifest overrides
@@ -122,26 +170,9 @@ func NewLocalRunner(out *output, manifest *Manifest, overrides map[string]string
 	}
 
 	// Create the concrete instances to run
-	instances := []*instance{}
-	for _, service := range manifest.Services() {
-		log_output, err := out.LogOutput(service.Name)
-		if err != nil {
-			return nil, fmt.Errorf("error getting log output: %w", err)
-		}
-		logs := &serviceLogs{
-			logRef: log_output,
-			path:   log_output.Name(),
-		}
-		component := FindComponent(service.ComponentName)
-		if component == nil {
-			return nil, fmt.Errorf("component not found '%s'", service.ComponentName)
-		}
-		instance := &instance{
-			service:   service,
-			logs:      logs,
-			component: component,
-		}
-		instances = append(instances, instance)
+	instances, err := newInstances(out, manifest)
+	if err != nil {
+		return nil, err
 	}
 
 	// download any local release artifacts for the services that require them
@@ -195,6 +226,7 @@ func NewLocalRunner(out *output, manifest *Manifest, overrides map[string]string
 		}
 	}
 
+	networkName := cfg.NetworkName
 	if networkName == "" {
 		networkName = defaultNetworkName
 	}
@@ -202,20 +234,23 @@ func NewLocalRunner(out *output, manifest *Manifest, overrides map[string]string
 		out:                  out,
 		manifest:             manifest,
 		client:               client,
+		runtime:              runtime,
+		minHostPort:          minHostPort,
 		reservedPorts:        map[int]bool{},
 		overrides:            overrides,
 		handles:              []*exec.Cmd{},
 		tasks:                tasks,
 		taskUpdateCh:         make(chan struct{}),
 		exitErr:              make(chan error, 2),
-		bindHostPortsLocally: bindHostPortsLocally,
+		bindHostPortsLocally: cfg.BindHostPortsLocally,
 		sessionID:            uuid.New().String(),
+		sessionStartedAt:     time.Now(),
 		networkName:          networkName,
 		instances:            instances,
-		labels:               labels,
+		labels:               cfg.Labels,
 	}
 
-	if interactive {
+	if cfg.Interactive {
 		go d.printStatus()
 
 		select {
@@ -330,7 +365,7 @@ func (d *LocalRunner) WaitForReady(ctx context.Context, timeout time.Duration) e
 
 		case <-time.After(1 * time.Second):
 			if d.AreReady() {
-				return nil
+				return d.resolveInstanceIPs(ctx)
 			}
 
 		case err := <-d.exitErr:
@@ -339,6 +374,58 @@ func (d *LocalRunner) WaitForReady(ctx context.Context, timeout time.Duration) e
 	}
 }
 
+// resolveInstanceIPs sets the address in the network of the session of the instances that
+// the other nodes peer with (see isELBootnode). The containers reach the services running
+// on the host through the network gateway.
+func (d *LocalRunner) resolveInstanceIPs(ctx context.Context) error {
+	var bootnodes []*instance
+	for _, instance := range d.instances {
+		if isELBootnode(instance.component) {
+			bootnodes = append(bootnodes, instance)
+		}
+	}
+	if len(bootnodes) == 0 {
+		return nil
+	}
+
+	containers, err := d.client.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", "playground.session="+d.sessionID)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the containers of the session: %w", err)
+	}
+	ips := map[string]string{}
+	for _, c := range containers {
+		if c.NetworkSettings == nil {
+			continue
+		}
+		if endpoint, ok := c.NetworkSettings.Networks[d.networkName]; ok {
+			ips[c.Labels["service"]] = endpoint.IPAddress
+		}
+	}
+
+	var gateway string
+	for _, instance := range bootnodes {
+		if !d.isHostService(instance.service.Name) {
+			instance.ip = ips[instance.service.Name]
+			continue
+		}
+		if gateway == "" {
+			info, err := d.client.NetworkInspect(ctx, d.networkName, network.InspectOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to inspect network %s: %w", d.networkName, err)
+			}
+			if len(info.IPAM.Config) == 0 {
+				return fmt.Errorf("network %s has no gateway", d.networkName)
+			}
+			gateway = info.IPAM.Config[0].Gateway
+		}
+		instance.ip = gateway
+		instance.hostPorts = true
+	}
+	return nil
+}
+
 func (d *LocalRunner) updateTaskStatus(name string, status string) {
 	d.tasksMtx.Lock()
 	defer d.tasksMtx.Unlock()
@@ -364,47 +451,23 @@ func (d *LocalRunner) ExitErr() <-chan error {
 
 func (d *LocalRunner) Stop() error {
 	// only stop the containers that belong to this session
-	containers, err := d.client.ContainerList(context.Background(), container.ListOptions{
-		Filters: filters.NewArgs(filters.Arg("label", fmt.Sprintf("playground.session=%s", d.sessionID))),
-	})
-	if err != nil {
-		return fmt.Errorf("error getting container list: %w", err)
-	}
-
-	var wg sync.WaitGroup
-	wg.Add(len(containers))
-
-	var errCh chan error
-	errCh = make(chan error, len(containers))
-
-	for _, cont := range containers {
-		go func(contID string) {
-			defer wg.Done()
-			if err := d.client.ContainerRemove(context.Background(), contID, container.RemoveOptions{
-				RemoveVolumes: true,
-				RemoveLinks:   false,
-				Force:         true,
-			}); err != nil {
-				errCh <- fmt.Errorf("error removing container: %w", err)
-			}
-		}(cont.ID)
+	if err := stopSessionContainers(d.client, d.sessionID); err != nil {
+		return err
 	}
 
-	wg.Wait()
-
 	// stop all the handles
 	for _, handle := range d.handles {
 		handle.Process.Kill()
 	}
 
-	close(errCh)
-
-	for err := range errCh {
-		if err != nil {
+	if d.createdNetwork {
+		if err := removeSessionNetwork(d.client, d.networkName); err != nil {
 			return err
 		}
 	}
-
+	if err := removeSession(d.sessionID); err != nil {
+		return fmt.Errorf("failed to remove session: %w", err)
+	}
 	return nil
 }
 
@@ -412,6 +475,10 @@ func (d *LocalRunner) Stop() error {
 // Note that we have to keep track of the port in 'reservedPorts' because
 // the port allocation happens before the services uses it and binds to it.
 func (d *LocalRunner) reservePort(startPort int, protocol string) int {
+	if startPort < d.minHostPort {
+		// the container still listens on the original port, only the host port changes
+		startPort += d.minHostPort
+	}
 	for i := startPort; i < startPort+1000; i++ {
 		if _, ok := d.reservedPorts[i]; ok {
 			continue
@@ -459,8 +526,6 @@ func (d *LocalRunner) getService(name string) *Service {
 // applyTemplate resolves the templates from the manifest (Dir, Port, Connect) into
 // the actual values for this specific docker execution.
 func (d *LocalRunner) applyTemplate(s *Service) ([]string, map[string]string, error) {
-	var input map[string]interface{}
-
 	resolvePort := func(name string, defaultPort int, protocol string) int {
 		// For {{Port "name" "defaultPort"}}:
 		// - Service runs on host: return the host port
@@ -510,41 +575,7 @@ func (d *LocalRunner) applyTemplate(s *Service) ([]string, map[string]string, er
 		},
 	}
 
-	runTemplate := func(arg string) (string, error) {
-		tpl, err := template.New("").Funcs(funcs).Parse(arg)
-		if err != nil {
-			return "", err
-		}
-
-		var out strings.Builder
-		if err := tpl.Execute(&out, input); err != nil {
-			return "", err
-		}
-
-		return out.String(), nil
-	}
-
-	// apply the templates to the arguments
-	var argsResult []string
-	for _, arg := range s.Args {
-		newArg, err := runTemplate(arg)
-		if err != nil {
-			return nil, nil, err
-		}
-		argsResult = append(argsResult, newArg)
-	}
-
-	// apply the templates to the environment variables
-	envs := map[string]string{}
-	for k, v := range s.Env {
-		newV, err := runTemplate(v)
-		if err != nil {
-			return nil, nil, err
-		}
-		envs[k] = newV
-	}
-
-	return argsResult, envs, nil
+	return resolveServiceTemplates(s, funcs)
 }
 
 func (d *LocalRunner) validateImageExists(image string) error {
@@ -568,28 +599,8 @@ func (d *LocalRunner) validateImageExists(image string) error {
 	return fmt.Errorf("image %s not found: %w", image)
 }
 
-func (d *LocalRunner) toDockerComposeService(s *Service) (map[string]interface{}, error) {
-	// apply the template again on the arguments to figure out the connections
-	// at this point all of them are valid, we just have to resolve them again. We assume for now
-	// everyone is going to be on docker at the same network.
-	args, envs, err := d.applyTemplate(s)
-	if err != nil {
-		return nil, fmt.Errorf("failed to apply template, err: %w", err)
-	}
-
-	// The containers have access to the full set of artifacts on the /artifacts folder
-	// so, we have to bind it as a volume on the container.
-	outputFolder, err := d.out.AbsoluteDstPath()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path for output folder: %w", err)
-	}
-
-	// Validate that the image exists
-	imageName := fmt.Sprintf("%s:%s", s.Image, s.Tag)
-	if err := d.validateImageExists(imageName); err != nil {
-		return nil, fmt.Errorf("failed to validate image %s: %w", imageName, err)
-	}
-
+// containerLabels returns the labels to apply to the container of the service
+func (d *LocalRunner) containerLabels(s *Service) map[string]string {
 	labels := map[string]string{
 		// It is important to use the playground label to identify the containers
 		// during the cleanup process
@@ -609,6 +620,17 @@ func (d *LocalRunner) toDockerComposeService(s *Service) (map[string]interface{}
 	for _, port := range s.Ports {
 		labels[fmt.Sprintf("port.%s", port.Name)] = fmt.Sprintf("%d", port.Port)
 	}
+	return labels
+}
+
+// containerVolumes returns the bind mounts (host path to container path) of the service
+func (d *LocalRunner) containerVolumes(s *Service) (map[string]string, error) {
+	// The containers have access to the full set of artifacts on the /artifacts folder
+	// so, we have to bind it as a volume on the container.
+	outputFolder, err := d.out.AbsoluteDstPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for output folder: %w", err)
+	}
 
 	// Use files mapped to figure out which files from the artifacts is using the service
 	volumes := map[string]string{
@@ -631,7 +653,45 @@ func (d *LocalRunner) toDockerComposeService(s *Service) (map[string]interface{}
 			volumes[volumeDirAbsPath] = localPath
 		}
 	}
+	return volumes, nil
+}
+
+// healthcheckTest returns the docker healthcheck test for the ready check
+func healthcheckTest(check *ReadyCheck) []string {
+	if check.QueryURL != "" {
+		// This is pretty much hardcoded for now.
+		return []string{"CMD-SHELL", "chmod +x /artifacts/scripts/query.sh && /artifacts/scripts/query.sh " + check.QueryURL}
+	}
+	return check.Test
+}
+
+func (d *LocalRunner) toDockerComposeService(s *Service) (map[string]interface{}, error) {
+	// apply the template again on the arguments to figure out the connections
+	// at this point all of them are valid, we just have to resolve them again. We assume for now
+	// everyone is going to be on docker at the same network.
+	args, envs, err := d.applyTemplate(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply template, err: %w", err)
+	}
+
+	// Validate that the image exists
+	imageName := fmt.Sprintf("%s:%s", s.Image, s.Tag)
+	if err := d.validateImageExists(imageName); err != nil {
+		return nil, fmt.Errorf("failed to validate image %s: %w", imageName, err)
+	}
 
+	// docker compose interpolates the variables in the command, escape them
+	// so that the shell scripts of the services can use them.
+	for i, arg := range args {
+		args[i] = strings.ReplaceAll(arg, "$", "$$")
+	}
+
+	labels := d.containerLabels(s)
+
+	volumes, err := d.containerVolumes(s)
+	if err != nil {
+		return nil, err
+	}
 	volumesInLine := []string{}
 	for k, v := range volumes {
 		volumesInLine = append(volumesInLine, fmt.Sprintf("%s:%s", k, v))
@@ -657,16 +717,8 @@ func (d *LocalRunner) toDockerComposeService(s *Service) (map[string]interface{}
 	}
 
 	if s.ReadyCheck != nil {
-		var test []string
-		if s.ReadyCheck.QueryURL != "" {
-			// This is pretty much hardcoded for now.
-			test = []string{"CMD-SHELL", "chmod +x /artifacts/scripts/query.sh && /artifacts/scripts/query.sh " + s.ReadyCheck.QueryURL}
-		} else {
-			test = s.ReadyCheck.Test
-		}
-
 		service["healthcheck"] = map[string]interface{}{
-			"test":         test,
+			"test":         healthcheckTest(s.ReadyCheck),
 			"interval":     s.ReadyCheck.Interval.String(),
 			"timeout":      s.ReadyCheck.Timeout.String(),
 			"retries":      s.ReadyCheck.Retries,
@@ -689,13 +741,13 @@ func (d *LocalRunner) toDockerComposeService(s *Service) (map[string]interface{}
 		service["depends_on"] = depends
 	}
 
-	if runtime.GOOS == "linux" {
+	if runtime.GOOS == "linux" || d.runtime == RuntimePodman {
 		// We rely on host.docker.internal as the DNS address for the host inside
 		// the container. But, this is only available on Macos and Windows.
 		// On Linux, you can use the IP address 172.17.0.1 to access the host.
 		// Thus, if we are running on Linux, we need to add an extra host entry.
 		service["extra_hosts"] = map[string]string{
-			"host.docker.internal": "172.17.0.1",
+			"host.docker.internal": d.runtime.hostGateway(),
 		}
 	}
 
@@ -711,12 +763,7 @@ func (d *LocalRunner) toDockerComposeService(s *Service) (map[string]interface{}
 				protocol = "/udp"
 			}
 
-			// CADDY Modification
-			// TODO(Odysseas): This feels like a hack here
-			// Only expose ports if Caddy is not enabled or this is the Caddy service itself
-			shouldExpose := !d.manifest.ctx.CaddyEnabled || s.Name == "caddy"
-
-			if shouldExpose {
+			if d.exposePorts(s) {
 				if d.bindHostPortsLocally {
 					ports = append(ports, fmt.Sprintf("127.0.0.1:%d:%d%s", p.HostPort, p.Port, protocol))
 				} else {
@@ -732,24 +779,21 @@ func (d *LocalRunner) toDockerComposeService(s *Service) (map[string]interface{}
 	return service, nil
 }
 
+// exposePorts returns whether the ports of the service are bound on the host machine
+func (d *LocalRunner) exposePorts(s *Service) bool {
+	// CADDY Modification
+	// TODO(Odysseas): This feels like a hack here
+	// Only expose ports if Caddy is not enabled or this is the Caddy service itself
+	return !d.manifest.ctx.CaddyEnabled || s.Name == "caddy"
+}
+
 func (d *LocalRunner) isHostService(name string) bool {
 	_, ok := d.overrides[name]
 	return ok
 }
 
-func (d *LocalRunner) generateDockerCompose() ([]byte, error) {
-	compose := map[string]interface{}{
-		// We create a new network to be used by all the services so that
-		// we can do DNS discovery between them.
-		"networks": map[string]interface{}{
-			d.networkName: map[string]interface{}{
-				"name": d.networkName,
-			},
-		},
-	}
-
-	services := map[string]interface{}{}
-
+// reserveServicePorts reserves a port on the host machine for each service port
+func (d *LocalRunner) reserveServicePorts() {
 	// for each service, reserve a port on the host machine. We use this ports
 	// both to have access to the services from localhost but also to do communication
 	// between services running inside docker and the ones running on the host machine.
@@ -772,6 +816,37 @@ func (d *LocalRunner) generateDockerCompose() ([]byte, error) {
 			}
 		}
 	}
+}
+
+func (d *LocalRunner) generateDockerCompose() ([]byte, error) {
+	exists, created, err := inspectSessionNetwork(d.client, d.networkName)
+	if err != nil {
+		return nil, err
+	}
+	d.createdNetwork = created
+
+	// We create a new network to be used by all the services so that
+	// we can do DNS discovery between them. An existing network is used as is.
+	composeNetwork := map[string]interface{}{
+		"name":   d.networkName,
+		"labels": map[string]string{"playground": "true"},
+	}
+	if exists {
+		composeNetwork = map[string]interface{}{
+			"name":     d.networkName,
+			"external": true,
+		}
+	}
+	compose := map[string]interface{}{
+		"networks": map[string]interface{}{
+			d.networkName: composeNetwork,
+		},
+	}
+
+	services := map[string]interface{}{}
+
+	d.reserveServicePorts()
+
 	for _, svc := range d.manifest.services {
 		if d.isHostService(svc.Name) {
 			// skip services that are going to be launched on host
@@ -793,6 +868,11 @@ func (d *LocalRunner) generateDockerCompose() ([]byte, error) {
 }
 
 func (d *LocalRunner) createVolume(service, volumeName string) (string, error) {
+	// a shared volume lives in the folder of the service that owns it
+	if owner, name, ok := splitSharedVolume(volumeName); ok {
+		service, volumeName = owner, name
+	}
+
 	// create the volume in the output folder
 	volumeDirAbsPath, err := d.out.CreateDir(fmt.Sprintf("volume-%s-%s", service, volumeName))
 	if err != nil {
@@ -852,8 +932,12 @@ func (d *LocalRunner) runOnHost(ss *Service) error {
 	cmd.Stdout = logOutput
 	cmd.Stderr = logOutput
 
+	// the process is started here so that its pid is registered in the session
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting host service %s: %w", ss.Name, err)
+	}
 	go func() {
-		if err := cmd.Run(); err != nil {
+		if err := cmd.Wait(); err != nil {
 			d.exitErr <- fmt.Errorf("error running host service %s: %w", ss.Name, err)
 		}
 	}()
@@ -897,7 +981,8 @@ func (d *LocalRunner) trackContainerStatusAndLogs() {
 	for {
 		select {
 		case event := <-eventCh:
-			name := event.Actor.Attributes["com.docker.compose.service"]
+			// the attributes of the event include the labels of the container
+			name := event.Actor.Attributes["service"]
 
 			switch event.Action {
 			case events.ActionStart:
@@ -916,6 +1001,14 @@ func (d *LocalRunner) trackContainerStatusAndLogs() {
 			case events.ActionHealthStatusHealthy:
 				d.updateTaskStatus(name, taskStatusHealthy)
 				log.Info("container is healthy", "name", name)
+
+			case events.ActionHealthStatus:
+				// some Podman versions do not include the status in the action, check it with inspect
+				info, err := d.client.ContainerInspect(context.Background(), event.Actor.ID)
+				if err == nil && info.State.Health != nil && info.State.Health.Status == container.Healthy {
+					d.updateTaskStatus(name, taskStatusHealthy)
+					log.Info("container is healthy", "name", name)
+				}
 			}
 
 		case err := <-errCh:
@@ -988,6 +1081,32 @@ func CreatePrometheusServices(manifest *Manifest, out *output) error {
 	return nil
 }
 
+func (d *LocalRunner) saveSession() error {
+	outputDir, err := d.out.AbsoluteDstPath()
+	if err != nil {
+		return err
+	}
+	services := []string{}
+	for _, svc := range d.manifest.services {
+		services = append(services, svc.Name)
+	}
+	hostPIDs := []int{}
+	for _, handle := range d.handles {
+		hostPIDs = append(hostPIDs, handle.Process.Pid)
+	}
+	return saveSession(&Session{
+		ID:        d.sessionID,
+		OutputDir: outputDir,
+		Network:   d.networkName,
+		Runtime:   d.runtime,
+		Services:  services,
+		HostPIDs:  hostPIDs,
+		StartedAt: d.sessionStartedAt,
+
+		CreatedNetwork: d.createdNetwork,
+	})
+}
+
 func (d *LocalRunner) Run() error {
 	go d.trackContainerStatusAndLogs()
 
@@ -1000,22 +1119,55 @@ func (d *LocalRunner) Run() error {
 		return fmt.Errorf("failed to write docker-compose.yaml: %w", err)
 	}
 
-	// generate the output log file for each service so that it is available after Run is done
-	for _, instance := range d.instances {
-		d.tasks[instance.service.Name].logs = instance.logs.logRef
-	}
+	d.initTaskLogs()
 
 	// First start the services that are running in docker-compose
-	cmd := exec.Command("docker", "compose", "-f", d.out.dst+"/docker-compose.yaml", "up", "-d")
+	composeCmd, env, err := d.runtime.composeCommand("-f", d.out.dst+"/docker-compose.yaml", "up", "-d")
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(composeCmd[0], composeCmd[1:]...)
+	cmd.Env = env
 
 	var errOut bytes.Buffer
 	cmd.Stderr = &errOut
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to run docker-compose: %w, err: %s", err, errOut.String())
+		return fmt.Errorf("failed to run %s compose: %w, err: %s", d.runtime, err, errOut.String())
+	}
+
+	// register the session so that it can be found later on by the 'status', 'logs' and 'stop' commands
+	if err := d.saveSession(); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
 	}
 
 	// Second, start the services that are running on the host machine
+	return d.runHostServices()
+}
+
+// initTaskLogs sets the output log file for each service so that it is available after Run is done
+func (d *LocalRunner) initTaskLogs() {
+	for _, instance := range d.instances {
+		d.tasks[instance.service.Name].logs = instance.logs.logRef
+	}
+}
+
+// runHostServices starts the services that are running on the host machine and registers
+// their processes in the session so that 'stop' kills them as well
+func (d *LocalRunner) runHostServices() error {
+	if err := d.startHostServices(); err != nil {
+		return err
+	}
+	if len(d.handles) == 0 {
+		return nil
+	}
+	if err := d.saveSession(); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+func (d *LocalRunner) startHostServices() error {
 	errCh := make(chan error)
 	go func() {
 		for _, svc := range d.manifest.services {
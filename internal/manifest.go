@@ -3,8 +3,10 @@ package internal
 import (
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -23,6 +25,12 @@ type Recipe interface {
 	Output(manifest *Manifest) map[string]interface{}
 }
 
+// RecipeValidator is a recipe that validates the combination of its flags before
+// the artifacts are built
+type RecipeValidator interface {
+	Validate() error
+}
+
 // Manifest describes a list of services and their dependencies
 type Manifest struct {
 	ctx *ExContext
@@ -89,6 +97,24 @@ func (s *Manifest) Services() []*Service {
 	return s.services
 }
 
+// ServiceConfig is a service that reads the other services from a config file instead of
+// its arguments. The config is written once all the services and their ports are known.
+type ServiceConfig interface {
+	WriteConfig(manifest *Manifest, service *Service) error
+}
+
+// WriteConfigs writes the config files of the services that implement ServiceConfig
+func (s *Manifest) WriteConfigs() error {
+	for _, ss := range s.services {
+		if configFn, ok := ss.component.(ServiceConfig); ok {
+			if err := configFn.WriteConfig(s, ss); err != nil {
+				return fmt.Errorf("failed to write the config of service %s: %w", ss.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
 // ReleaseService is a service that can also be runned as an artifact in the host machine
 type ReleaseService interface {
 	ReleaseArtifact() *release
@@ -97,6 +123,7 @@ type ReleaseService interface {
 func (s *Manifest) AddService(name string, srv ServiceGen) {
 	service := s.NewService(name)
 	service.ComponentName = srv.Name()
+	service.component = srv
 	srv.Run(service, s.ctx)
 
 	s.services = append(s.services, service)
@@ -152,6 +179,29 @@ func (s *Manifest) Validate() error {
 		}
 	}
 
+	// validate that the shared volumes are defined by their owner
+	for _, ss := range s.services {
+		for _, volumeName := range ss.VolumesMapped {
+			owner, name, ok := splitSharedVolume(volumeName)
+			if !ok {
+				continue
+			}
+			ownerService, ok := s.GetService(owner)
+			if !ok {
+				return fmt.Errorf("service %s mounts a volume of service %s, but it is not defined", ss.Name, owner)
+			}
+			found := false
+			for _, ownerVolumeName := range ownerService.VolumesMapped {
+				if ownerVolumeName == name {
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("service %s mounts the volume %s of service %s, but it does not define it", ss.Name, name, owner)
+			}
+		}
+	}
+
 	// validate that the mounts are correct
 	for _, ss := range s.services {
 		for _, fileNameRef := range ss.FilesMapped {
@@ -266,6 +316,10 @@ type Service struct {
 	Entrypoint string `json:"entrypoint,omitempty"`
 
 	Privileged bool `json:"privileged,omitempty"`
+
+	// component is the component that generated the service. It is used to
+	// run the hooks (i.e. ServiceReady) with the same parameters as the recipe.
+	component ServiceGen
 }
 
 type instance struct {
@@ -273,6 +327,28 @@ type instance struct {
 
 	logs      *serviceLogs
 	component ServiceGen
+
+	// manifest is used by the hooks to reach the other services
+	manifest *Manifest
+
+	// ip is the address of the service in the network of the runner and hostPorts is
+	// set if the service listens on its host ports (i.e. it runs on the host machine).
+	// The ready hooks use them to connect the nodes with each other.
+	ip        string
+	hostPorts bool
+}
+
+// peerAddr returns the address of the port of the service for the other services
+// in the network of the runner
+func (i *instance) peerAddr(portName string) (string, error) {
+	if i.ip == "" {
+		return "", fmt.Errorf("the address of %s in the network is not known", i.service.Name)
+	}
+	port := i.service.MustGetPort(portName)
+	if i.hostPorts {
+		return net.JoinHostPort(i.ip, strconv.Itoa(port.HostPort)), nil
+	}
+	return net.JoinHostPort(i.ip, strconv.Itoa(port.Port)), nil
 }
 
 type DependsOnCondition string
@@ -410,6 +486,25 @@ func (s *Service) WithVolume(name string, localPath string) *Service {
 	return s
 }
 
+// WithSharedVolume mounts the volume of another service (i.e. to read its datadir).
+// The volume is created by the service that owns it.
+func (s *Service) WithSharedVolume(service string, name string, localPath string) *Service {
+	return s.WithVolume(sharedVolumeName(service, name), localPath)
+}
+
+// sharedVolumeName returns the name used in VolumesMapped for a volume of another service
+func sharedVolumeName(service, name string) string {
+	return service + ":" + name
+}
+
+// splitSharedVolume returns the owner service and the name of a shared volume
+func splitSharedVolume(volumeName string) (string, string, bool) {
+	if filepath.IsAbs(volumeName) {
+		return "", "", false
+	}
+	return strings.Cut(volumeName, ":")
+}
+
 // WithAbsoluteVolume adds a volume mapping using an absolute path on the host.
 // This is useful for binding system paths like /var/run/docker.sock.
 // The path must be absolute and will be used as-is without any modification.
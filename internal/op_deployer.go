@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	_ "embed"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+//go:embed intent.toml.tmpl
+var opDeployerIntentContent []byte
+
+// opDeployerContractsLocator is the location of the contract artifacts used by the
+// embedded deployment (see utils/intent.toml)
+const opDeployerContractsLocator = "https://storage.googleapis.com/oplabs-contract-artifacts/artifacts-v1-c193a1863182092bc6cb723e523e8313a0f4b6e9c9636513927f1db74c047c15.tar.gz"
+
+// defaultOpL2ChainID is the chain id of the embedded L2 genesis
+const defaultOpL2ChainID = 2151908
+
+// opDeployerTimeout is the maximum time to deploy the contracts, op-deployer has to
+// download the contract artifacts the first time
+const opDeployerTimeout = 10 * time.Minute
+
+// opDeployment is the output of op-deployer, it has the same format as the
+// embedded utils/state.json, utils/genesis.json and utils/rollup.json files.
+// There is one genesis and rollup config for each chain.
+type opDeployment struct {
+	State   []byte
+	Genesis [][]byte
+	Rollup  [][]byte
+}
+
+// opDeployerState is the state of op-deployer (utils/state.json) after the deployment
+type opDeployerState struct {
+	L1StateDump   string `json:"l1StateDump"`
+	AppliedIntent struct {
+		Chains []*opChainIntent `json:"chains"`
+	} `json:"appliedIntent"`
+	OpChainDeployments []struct {
+		DisputeGameFactoryProxyAddress gethcommon.Address `json:"disputeGameFactoryProxyAddress"`
+	} `json:"opChainDeployments"`
+}
+
+// opChainIntent is the intent of an op chain deployed by op-deployer
+type opChainIntent struct {
+	BaseFeeVaultRecipient      gethcommon.Address `json:"baseFeeVaultRecipient"`
+	L1FeeVaultRecipient        gethcommon.Address `json:"l1FeeVaultRecipient"`
+	SequencerFeeVaultRecipient gethcommon.Address `json:"sequencerFeeVaultRecipient"`
+}
+
+// runOpDeployer deploys the L2 contracts of the chains with op-deployer on top of the L1 genesis
+// and inspects the L2 genesis and the rollup config of each chain. The i-th chain has the chain
+// id of the params plus i and its own batcher (see opBatcherPrivateKey). The source is either
+// an op-deployer binary (a path or a name in $PATH) or a container image.
+// All the files are written in workDir.
+func runOpDeployer(source string, workDir string, l1ChainID uint64, params *OpChainParams, chains int) (*opDeployment, error) {
+	intent, err := opDeployerIntent(l1ChainID, params, chains)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(workDir, "intent.toml"), intent, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "state.json"), []byte(`{"version": 1}`), 0644); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opDeployerTimeout)
+	defer cancel()
+
+	if err := opDeployerCommand(ctx, source, workDir, "apply", "--workdir", ".", "--deployment-target", "genesis"); err != nil {
+		return nil, err
+	}
+	deployment := &opDeployment{}
+	if deployment.State, err = os.ReadFile(filepath.Join(workDir, "state.json")); err != nil {
+		return nil, fmt.Errorf("failed to read the op-deployer state: %w", err)
+	}
+
+	for i := 0; i < chains; i++ {
+		genesisName, rollupName := opChainArtifacts(i)
+		chainID := strconv.FormatUint(params.ChainID+uint64(i), 10)
+		for _, args := range [][]string{
+			{"inspect", "genesis", "--workdir", ".", "--outfile", genesisName, chainID},
+			{"inspect", "rollup", "--workdir", ".", "--outfile", rollupName, chainID},
+		} {
+			if err := opDeployerCommand(ctx, source, workDir, args...); err != nil {
+				return nil, err
+			}
+		}
+
+		genesis, err := os.ReadFile(filepath.Join(workDir, genesisName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the op-deployer genesis: %w", err)
+		}
+		rollup, err := os.ReadFile(filepath.Join(workDir, rollupName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the op-deployer rollup config: %w", err)
+		}
+		deployment.Genesis = append(deployment.Genesis, genesis)
+		deployment.Rollup = append(deployment.Rollup, rollup)
+	}
+	return deployment, nil
+}
+
+// opDeployerIntent renders the op-deployer intent of the chains. The owner roles use the roles
+// key and the batcher, proposer and challenger roles have their own accounts.
+func opDeployerIntent(l1ChainID uint64, params *OpChainParams, chains int) ([]byte, error) {
+	roles, err := opAccountAddress(opRolesPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	proposer, err := opAccountAddress(opProposerPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	challenger, err := opAccountAddress(opChallengerPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// the fees go to the chain operator unless the params set the recipients
+	recipient := func(addr gethcommon.Address) string {
+		if addr == (gethcommon.Address{}) {
+			return roles.Hex()
+		}
+		return addr.Hex()
+	}
+	chainIntents := []map[string]string{}
+	for i := 0; i < chains; i++ {
+		batcher, err := opBatcherAddress(i)
+		if err != nil {
+			return nil, err
+		}
+		chainIntents = append(chainIntents, map[string]string{
+			"ID":      gethcommon.BigToHash(new(big.Int).SetUint64(params.ChainID + uint64(i))).Hex(),
+			"Batcher": batcher.Hex(),
+		})
+	}
+	intent, err := applyTemplate2(opDeployerIntentContent, map[string]interface{}{
+		"L1ChainID":                  l1ChainID,
+		"Chains":                     chainIntents,
+		"Roles":                      roles.Hex(),
+		"Proposer":                   proposer.Hex(),
+		"Challenger":                 challenger.Hex(),
+		"ContractsLocator":           opDeployerContractsLocator,
+		"GasLimit":                   hexutil.Uint64(params.GasLimit).String(),
+		"EIP1559Elasticity":          params.EIP1559Elasticity,
+		"EIP1559Denominator":         params.EIP1559Denominator,
+		"EIP1559DenominatorCanyon":   params.EIP1559DenominatorCanyon,
+		"BaseFeeVaultRecipient":      recipient(params.BaseFeeVaultRecipient),
+		"L1FeeVaultRecipient":        recipient(params.L1FeeVaultRecipient),
+		"SequencerFeeVaultRecipient": recipient(params.SequencerFeeVaultRecipient),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the intent: %w", err)
+	}
+	return intent, nil
+}
+
+// opChainArtifacts returns the names of the L2 genesis and rollup config artifacts of the
+// i-th L2 chain. The first chain uses the names of the single chain setup.
+func opChainArtifacts(i int) (string, string) {
+	if i == 0 {
+		return "l2-genesis.json", "rollup.json"
+	}
+	return fmt.Sprintf("l2-genesis-%d.json", i), fmt.Sprintf("rollup-%d.json", i)
+}
+
+// opDeployerCommand runs an op-deployer command inside the workDir. If the source is not
+// a binary, it runs the command inside a container of the image with the workDir mounted.
+func opDeployerCommand(ctx context.Context, source string, workDir string, args ...string) error {
+	var cmd *exec.Cmd
+	if binPath, err := exec.LookPath(source); err == nil {
+		cmd = exec.CommandContext(ctx, binPath, args...)
+		cmd.Dir = workDir
+	} else {
+		// run the container as the current user so that the output can be removed later on.
+		// op-deployer caches the contract artifacts in the home directory.
+		dockerArgs := []string{
+			"run", "--rm",
+			"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+			"-e", "HOME=/workdir",
+			"-v", workDir + ":/workdir",
+			"-w", "/workdir",
+			"--entrypoint", "op-deployer",
+			source,
+		}
+		cmd = exec.CommandContext(ctx, "docker", append(dockerArgs, args...)...)
+	}
+
+	var errOut bytes.Buffer
+	cmd.Stderr = &errOut
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run op-deployer %s: %w, err: %s", args[0], err, errOut.String())
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+func TestOpDeployerIntent(t *testing.T) {
+	recipient := gethcommon.HexToAddress("0x0000000000000000000000000000000000000001")
+	params := &OpChainParams{
+		ChainID:                  4242,
+		GasLimit:                 90_000_000,
+		EIP1559Elasticity:        4,
+		EIP1559Denominator:       100,
+		EIP1559DenominatorCanyon: 200,
+		L1FeeVaultRecipient:      recipient,
+	}
+	data, err := opDeployerIntent(1337, params, 2)
+	if err != nil {
+		t.Fatalf("failed to render the intent: %v", err)
+	}
+
+	type roles struct {
+		L1ProxyAdminOwner string `toml:"l1ProxyAdminOwner"`
+		Batcher           string `toml:"batcher"`
+		Proposer          string `toml:"proposer"`
+		Challenger        string `toml:"challenger"`
+	}
+	var intent struct {
+		L1ChainID          uint64 `toml:"l1ChainID"`
+		L1ContractsLocator string `toml:"l1ContractsLocator"`
+		SuperchainRoles    struct {
+			ProxyAdminOwner string `toml:"proxyAdminOwner"`
+		} `toml:"superchainRoles"`
+		Chains []struct {
+			ID                       string `toml:"id"`
+			BaseFeeVaultRecipient    string `toml:"baseFeeVaultRecipient"`
+			L1FeeVaultRecipient      string `toml:"l1FeeVaultRecipient"`
+			EIP1559Elasticity        uint64 `toml:"eip1559Elasticity"`
+			EIP1559Denominator       uint64 `toml:"eip1559Denominator"`
+			EIP1559DenominatorCanyon uint64 `toml:"eip1559DenominatorCanyon"`
+			Roles                    roles  `toml:"roles"`
+			DeployOverrides          struct {
+				L2GenesisBlockGasLimit string `toml:"l2GenesisBlockGasLimit"`
+			} `toml:"deployOverrides"`
+		} `toml:"chains"`
+	}
+	if _, err := toml.Decode(string(data), &intent); err != nil {
+		t.Fatalf("failed to decode the intent: %v\n%s", err, data)
+	}
+
+	address := func(key string) string {
+		addr, err := opAccountAddress(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr.Hex()
+	}
+	owner := address(opRolesPrivateKey)
+
+	if intent.L1ChainID != 1337 || intent.L1ContractsLocator != opDeployerContractsLocator || intent.SuperchainRoles.ProxyAdminOwner != owner {
+		t.Fatalf("unexpected intent %+v", intent)
+	}
+	if len(intent.Chains) != 2 {
+		t.Fatalf("expected 2 chains, got %d", len(intent.Chains))
+	}
+	for i, chain := range intent.Chains {
+		// the chain ids are consecutive
+		if id := gethcommon.HexToHash(chain.ID).Big().Uint64(); id != params.ChainID+uint64(i) {
+			t.Fatalf("expected chain id %d, got %d", params.ChainID+uint64(i), id)
+		}
+		if chain.EIP1559Elasticity != 4 || chain.EIP1559Denominator != 100 || chain.EIP1559DenominatorCanyon != 200 {
+			t.Fatalf("unexpected eip1559 params of chain %d: %+v", i, chain)
+		}
+		if chain.DeployOverrides.L2GenesisBlockGasLimit != "0x55d4a80" {
+			t.Fatalf("unexpected gas limit of chain %d: %s", i, chain.DeployOverrides.L2GenesisBlockGasLimit)
+		}
+
+		// the fees go to the chain operator unless the params set the recipient
+		if chain.BaseFeeVaultRecipient != owner || chain.L1FeeVaultRecipient != recipient.Hex() {
+			t.Fatalf("unexpected fee vault recipients of chain %d: %+v", i, chain)
+		}
+
+		// the roles of the intent are the accounts of the services
+		batcher, err := opBatcherAddress(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := roles{
+			L1ProxyAdminOwner: owner,
+			Batcher:           batcher.Hex(),
+			Proposer:          address(opProposerPrivateKey),
+			Challenger:        address(opChallengerPrivateKey),
+		}
+		if chain.Roles != expected {
+			t.Fatalf("expected roles %+v for chain %d, got %+v", expected, i, chain.Roles)
+		}
+	}
+	if intent.Chains[0].Roles.Batcher == intent.Chains[1].Roles.Batcher {
+		t.Fatal("expected each chain to have its own batcher")
+	}
+}
+
+func TestOpDeployerFailure(t *testing.T) {
+	// the embedded deployment is the fallback if it matches the requested chain
+	if _, err := NewArtifactsBuilder().
+		OutputDir(t.TempDir()).
+		OpDeployer("false").
+		Build(); err != nil {
+		t.Fatalf("expected the embedded deployment as fallback, got %v", err)
+	}
+
+	cases := []struct {
+		builder *ArtifactsBuilder
+		reason  string
+	}{
+		{NewArtifactsBuilder().OpChainParams("", &OpChainParams{ChainID: 4242}), "chain id 4242"},
+		{NewArtifactsBuilder().OpChainParams("", &OpChainParams{GasLimit: 90_000_000}), "gas limit 90000000"},
+		{NewArtifactsBuilder().L2Chains(2), "single chain"},
+		{NewArtifactsBuilder().OpProposer(true), "op-proposer"},
+	}
+	for _, c := range cases {
+		_, err := c.builder.OutputDir(t.TempDir()).OpDeployer("false").Build()
+		if err == nil || !strings.Contains(err.Error(), "failed to deploy the L2 contracts") || !strings.Contains(err.Error(), c.reason) {
+			t.Fatalf("expected the op-deployer error because of the %s, got %v", c.reason, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	flag "github.com/spf13/pflag"
+)
+
+// OpChainParams are the parameters of the L2 chain. They are applied to the L2 genesis,
+// the rollup config and the op-deployer intent.
+type OpChainParams struct {
+	ChainID uint64 `json:"chain_id"`
+
+	// GasLimit is the gas limit of the genesis block and of the system config
+	GasLimit uint64 `json:"gas_limit"`
+
+	// EIP-1559 parameters of the base fee
+	EIP1559Elasticity        uint64 `json:"eip1559_elasticity"`
+	EIP1559Denominator       uint64 `json:"eip1559_denominator"`
+	EIP1559DenominatorCanyon uint64 `json:"eip1559_denominator_canyon"`
+
+	// Recipients of the fee vaults. If they are not set, the fees go to
+	// the recipients of the deployment (the chain operator).
+	BaseFeeVaultRecipient      gethcommon.Address `json:"base_fee_vault_recipient"`
+	L1FeeVaultRecipient        gethcommon.Address `json:"l1_fee_vault_recipient"`
+	SequencerFeeVaultRecipient gethcommon.Address `json:"sequencer_fee_vault_recipient"`
+}
+
+// DefaultOpChainParams returns the parameters of the embedded deployment
+func DefaultOpChainParams() *OpChainParams {
+	return &OpChainParams{
+		ChainID:                  defaultOpL2ChainID,
+		GasLimit:                 60_000_000,
+		EIP1559Elasticity:        6,
+		EIP1559Denominator:       50,
+		EIP1559DenominatorCanyon: 250,
+	}
+}
+
+// LoadOpChainParams reads a JSON file with the L2 chain params on top of the defaults
+func LoadOpChainParams(path string) (*OpChainParams, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read l2 params file: %w", err)
+	}
+	params := DefaultOpChainParams()
+	if err := decodeStrict(data, params); err != nil {
+		return nil, fmt.Errorf("failed to decode l2 params file: %w", err)
+	}
+	return params, nil
+}
+
+// Merge overrides the params with the values that are set in other
+func (o *OpChainParams) Merge(other *OpChainParams) {
+	for _, val := range []struct {
+		dst *uint64
+		src uint64
+	}{
+		{&o.ChainID, other.ChainID},
+		{&o.GasLimit, other.GasLimit},
+		{&o.EIP1559Elasticity, other.EIP1559Elasticity},
+		{&o.EIP1559Denominator, other.EIP1559Denominator},
+		{&o.EIP1559DenominatorCanyon, other.EIP1559DenominatorCanyon},
+	} {
+		if val.src != 0 {
+			*val.dst = val.src
+		}
+	}
+	for _, val := range []struct {
+		dst *gethcommon.Address
+		src gethcommon.Address
+	}{
+		{&o.BaseFeeVaultRecipient, other.BaseFeeVaultRecipient},
+		{&o.L1FeeVaultRecipient, other.L1FeeVaultRecipient},
+		{&o.SequencerFeeVaultRecipient, other.SequencerFeeVaultRecipient},
+	} {
+		if val.src != (gethcommon.Address{}) {
+			*val.dst = val.src
+		}
+	}
+}
+
+func (o *OpChainParams) Validate() error {
+	if o.ChainID == 0 {
+		return fmt.Errorf("the l2 chain id must be set")
+	}
+	if o.ChainID == 1337 {
+		return fmt.Errorf("the l2 chain id %d is the chain id of the L1", o.ChainID)
+	}
+	if o.GasLimit < 5000 {
+		return fmt.Errorf("the l2 gas limit must be at least 5000, got %d", o.GasLimit)
+	}
+	if o.EIP1559Elasticity == 0 || o.EIP1559Denominator == 0 || o.EIP1559DenominatorCanyon == 0 {
+		return fmt.Errorf("the eip1559 elasticity and denominators must be positive")
+	}
+	return nil
+}
+
+// checkEmbeddedDeployment returns an error if the embedded deployment does not match the params.
+// It has a single chain, and the chain id and the gas limit are also part of its L1 contracts
+// (the deployment state and the SystemConfig), only op-deployer can change them. The other
+// params only change the L2 genesis and the rollup config.
+func (o *OpChainParams) checkEmbeddedDeployment(l2Chains int) error {
+	embedded := DefaultOpChainParams()
+	if l2Chains > 1 {
+		return fmt.Errorf("the embedded deployment has a single chain, got %d", l2Chains)
+	}
+	if o.ChainID != 0 && o.ChainID != embedded.ChainID {
+		return fmt.Errorf("the l2 chain id %d differs from the embedded deployment (%d)", o.ChainID, embedded.ChainID)
+	}
+	if o.GasLimit != 0 && o.GasLimit != embedded.GasLimit {
+		return fmt.Errorf("the l2 gas limit %d differs from the embedded deployment (%d)", o.GasLimit, embedded.GasLimit)
+	}
+	return nil
+}
+
+// opChainParamsFlags registers the flags to override the L2 chain params. The flags
+// take precedence over the values of the params file.
+func opChainParamsFlags(flags *flag.FlagSet, file *string, params *OpChainParams) {
+	flags.StringVar(file, "l2-params", "", "JSON file with the L2 chain params (chain_id, gas_limit, eip1559_*, *_fee_vault_recipient)")
+	flags.Uint64Var(&params.ChainID, "l2-chain-id", 0, "chain id of the L2 (defaults to 2151908, requires --op-deployer)")
+	flags.Uint64Var(&params.GasLimit, "l2-gas-limit", 0, "gas limit of the L2 blocks (defaults to 60000000, requires --op-deployer)")
+	flags.Uint64Var(&params.EIP1559Elasticity, "l2-eip1559-elasticity", 0, "eip1559 elasticity of the L2 (defaults to 6)")
+	flags.Uint64Var(&params.EIP1559Denominator, "l2-eip1559-denominator", 0, "eip1559 denominator of the L2 (defaults to 50)")
+	flags.Uint64Var(&params.EIP1559DenominatorCanyon, "l2-eip1559-denominator-canyon", 0, "eip1559 denominator of the L2 after canyon (defaults to 250)")
+	flags.Var(addressValue{&params.BaseFeeVaultRecipient}, "l2-base-fee-vault-recipient", "recipient of the L2 base fee vault")
+	flags.Var(addressValue{&params.L1FeeVaultRecipient}, "l2-l1-fee-vault-recipient", "recipient of the L2 l1 fee vault")
+	flags.Var(addressValue{&params.SequencerFeeVaultRecipient}, "l2-sequencer-fee-vault-recipient", "recipient of the L2 sequencer fee vault")
+}
+
+// feeVaultOverride replaces the recipient of a fee vault predeploy
+type feeVaultOverride struct {
+	// impl is the implementation of the predeploy in the L2 genesis
+	impl gethcommon.Address
+
+	// old is the recipient of the deployment and new the one from the params
+	old, new gethcommon.Address
+}
+
+// feeVaultOverrides returns the fee vaults whose recipient is changed by the params
+func (o *OpChainParams) feeVaultOverrides(deployed *opChainIntent) []feeVaultOverride {
+	overrides := []feeVaultOverride{}
+	for _, vault := range []feeVaultOverride{
+		{gethcommon.HexToAddress("0xc0d3c0d3c0d3c0d3c0d3c0d3c0d3c0d3c0d30019"), deployed.BaseFeeVaultRecipient, o.BaseFeeVaultRecipient},
+		{gethcommon.HexToAddress("0xc0d3c0d3c0d3c0d3c0d3c0d3c0d3c0d3c0d3001a"), deployed.L1FeeVaultRecipient, o.L1FeeVaultRecipient},
+		{gethcommon.HexToAddress("0xc0d3c0d3c0d3c0d3c0d3c0d3c0d3c0d3c0d30011"), deployed.SequencerFeeVaultRecipient, o.SequencerFeeVaultRecipient},
+	} {
+		if vault.new != (gethcommon.Address{}) && vault.new != vault.old {
+			overrides = append(overrides, vault)
+		}
+	}
+	return overrides
+}
+
+// applyFeeVaultOverrides adds to the alloc overrides of the L2 genesis the code of the fee vault
+// implementations with the new recipients. The recipient is an immutable of the implementation,
+// so it is embedded in the bytecode as a 32 bytes word.
+func applyFeeVaultOverrides(genesis []byte, overrides []feeVaultOverride, allocs map[string]interface{}) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	var gen struct {
+		Alloc map[string]json.RawMessage `json:"alloc"`
+	}
+	if err := json.Unmarshal(genesis, &gen); err != nil {
+		return fmt.Errorf("failed to unmarshal the l2 genesis: %w", err)
+	}
+
+	for _, override := range overrides {
+		// use the same key as the genesis, otherwise the merge would add a second entry for the address
+		var key string
+		for k := range gen.Alloc {
+			if gethcommon.HexToAddress(k) == override.impl {
+				key = k
+				break
+			}
+		}
+		if key == "" {
+			return fmt.Errorf("fee vault implementation %s not found in the l2 genesis", override.impl)
+		}
+
+		var account struct {
+			Code hexutil.Bytes `json:"code"`
+		}
+		if err := json.Unmarshal(gen.Alloc[key], &account); err != nil {
+			return fmt.Errorf("failed to unmarshal the fee vault %s: %w", override.impl, err)
+		}
+
+		oldWord := gethcommon.LeftPadBytes(override.old.Bytes(), 32)
+		if !bytes.Contains(account.Code, oldWord) {
+			return fmt.Errorf("recipient %s not found in the code of the fee vault %s", override.old, override.impl)
+		}
+		code := bytes.ReplaceAll(account.Code, oldWord, gethcommon.LeftPadBytes(override.new.Bytes(), 32))
+
+		allocs[key] = map[string]interface{}{
+			"code": hexutil.Bytes(code).String(),
+		}
+	}
+	return nil
+}
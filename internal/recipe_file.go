@@ -0,0 +1,250 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/BurntSushi/toml"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+var _ Recipe = &RecipeFile{}
+
+// RecipeFile is a recipe loaded from a YAML or TOML file. It describes the topology as
+// a list of services built from the components registered in the catalog.
+type RecipeFile struct {
+	RecipeName        string               `json:"name"`
+	RecipeDescription string               `json:"description"`
+	ArtifactsConfig   RecipeFileArtifacts  `json:"artifacts"`
+	ServicesConfig    []*RecipeFileService `json:"services"`
+
+	// components are the component instances (one per service) with the
+	// parameters from the file already applied
+	components []ServiceGen
+}
+
+// RecipeFileArtifacts are the options for the artifacts builder
+type RecipeFileArtifacts struct {
+	LatestL1Fork bool    `json:"latest_l1_fork"`
+	LatestL2Fork *uint64 `json:"latest_l2_fork"`
+	L2BlockTime  uint64  `json:"l2_block_time"`
+
+	// Validators and ValidatorClients configure the number of validators and the number
+	// of keystores (data_validator_<i>) they are split into
+	Validators       int `json:"validators"`
+	ValidatorClients int `json:"validator_clients"`
+}
+
+// RecipeFileService describes a service in the recipe file
+type RecipeFileService struct {
+	// Name is the name of the service in the manifest
+	Name string `json:"name"`
+
+	// Component is the name of the component in the catalog (i.e. 'reth', 'op-node')
+	Component string `json:"component"`
+
+	// Params are the values for the fields of the component struct
+	Params json.RawMessage `json:"params"`
+
+	Args      []string               `json:"args"`
+	Env       map[string]string      `json:"env"`
+	Ports     []*RecipeFilePort      `json:"ports"`
+	DependsOn []*RecipeFileDependsOn `json:"depends_on"`
+}
+
+type RecipeFilePort struct {
+	Name     string `json:"name"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+type RecipeFileDependsOn struct {
+	Name      string             `json:"name"`
+	Condition DependsOnCondition `json:"condition"`
+}
+
+// LoadRecipeFile reads a recipe file. The format is derived from the file extension
+// (.yaml, .yml or .toml).
+func LoadRecipeFile(path string) (*RecipeFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipe file: %w", err)
+	}
+
+	// decode the file into a generic map first and then re-encode it as JSON so that
+	// both formats share the same struct tags and the component params can be decoded
+	// directly into the component structs.
+	var raw map[string]interface{}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		var obj map[interface{}]interface{}
+		if err := yaml.Unmarshal(data, &obj); err != nil {
+			return nil, fmt.Errorf("failed to decode yaml recipe file: %w", err)
+		}
+		raw = normalizeYAML(obj).(map[string]interface{})
+	case ".toml":
+		if _, err := toml.Decode(string(data), &raw); err != nil {
+			return nil, fmt.Errorf("failed to decode toml recipe file: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported recipe file extension '%s', expected .yaml, .yml or .toml", filepath.Ext(path))
+	}
+
+	rawJSON, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var recipe RecipeFile
+	if err := decodeStrict(rawJSON, &recipe); err != nil {
+		return nil, fmt.Errorf("failed to decode recipe file: %w", err)
+	}
+	if err := recipe.init(); err != nil {
+		return nil, err
+	}
+	return &recipe, nil
+}
+
+// init validates the services and creates the component instances for each one of them
+func (r *RecipeFile) init() error {
+	if r.RecipeName == "" {
+		return fmt.Errorf("recipe file does not have a name")
+	}
+	if len(r.ServicesConfig) == 0 {
+		return fmt.Errorf("recipe file does not have any services")
+	}
+
+	names := map[string]bool{}
+	for _, svc := range r.ServicesConfig {
+		if svc.Name == "" {
+			return fmt.Errorf("service with component '%s' does not have a name", svc.Component)
+		}
+		if names[svc.Name] {
+			return fmt.Errorf("service '%s' is defined twice", svc.Name)
+		}
+		names[svc.Name] = true
+
+		component := FindComponent(svc.Component)
+		if component == nil {
+			return fmt.Errorf("service '%s': component '%s' not found", svc.Name, svc.Component)
+		}
+
+		// the catalog stores a single instance for each component, create a new one
+		// to apply the parameters of this service.
+		instance := reflect.New(reflect.TypeOf(component).Elem()).Interface().(ServiceGen)
+		if len(svc.Params) != 0 && string(svc.Params) != "null" {
+			if err := decodeStrict(svc.Params, instance); err != nil {
+				return fmt.Errorf("service '%s': failed to decode params for component '%s': %w", svc.Name, svc.Component, err)
+			}
+		}
+		r.components = append(r.components, instance)
+
+		for _, port := range svc.Ports {
+			if port.Protocol != "" && port.Protocol != ProtocolTCP && port.Protocol != ProtocolUDP {
+				return fmt.Errorf("service '%s': port '%s' has an invalid protocol '%s'", svc.Name, port.Name, port.Protocol)
+			}
+		}
+		for _, dep := range svc.DependsOn {
+			if dep.Condition != "" && dep.Condition != DependsOnConditionRunning && dep.Condition != DependsOnConditionHealthy {
+				return fmt.Errorf("service '%s': invalid depends_on condition '%s'", svc.Name, dep.Condition)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *RecipeFile) Name() string {
+	return r.RecipeName
+}
+
+func (r *RecipeFile) Description() string {
+	return r.RecipeDescription
+}
+
+func (r *RecipeFile) Flags() *flag.FlagSet {
+	return flag.NewFlagSet(r.RecipeName, flag.ContinueOnError)
+}
+
+func (r *RecipeFile) Artifacts() *ArtifactsBuilder {
+	builder := NewArtifactsBuilder()
+	builder.ApplyLatestL1Fork(r.ArtifactsConfig.LatestL1Fork)
+	builder.ApplyLatestL2Fork(r.ArtifactsConfig.LatestL2Fork)
+	if r.ArtifactsConfig.L2BlockTime != 0 {
+		builder.OpBlockTime(r.ArtifactsConfig.L2BlockTime)
+	}
+	if r.ArtifactsConfig.Validators != 0 || r.ArtifactsConfig.ValidatorClients != 0 {
+		validators, clients := r.ArtifactsConfig.Validators, r.ArtifactsConfig.ValidatorClients
+		if validators == 0 {
+			validators = defaultValidatorCount
+		}
+		if clients == 0 {
+			clients = 1
+		}
+		builder.Validators(validators, clients)
+	}
+	return builder
+}
+
+func (r *RecipeFile) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
+	svcManager := NewManifest(ctx, artifacts.Out)
+
+	for i, svcConfig := range r.ServicesConfig {
+		svcManager.AddService(svcConfig.Name, r.components[i])
+
+		svc := svcManager.MustGetService(svcConfig.Name)
+		svc.WithArgs(svcConfig.Args...)
+		for k, v := range svcConfig.Env {
+			svc.WithEnv(k, v)
+		}
+		for _, port := range svcConfig.Ports {
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = ProtocolTCP
+			}
+			svc.WithPort(port.Name, port.Port, protocol)
+		}
+		for _, dep := range svcConfig.DependsOn {
+			condition := dep.Condition
+			if condition == "" {
+				condition = DependsOnConditionRunning
+			}
+			svc.DependsOn = append(svc.DependsOn, DependsOn{Name: dep.Name, Condition: condition})
+		}
+	}
+	return svcManager
+}
+
+func (r *RecipeFile) Output(manifest *Manifest) map[string]interface{} {
+	return prefundedAccountsOutput(manifest)
+}
+
+// decodeStrict decodes the json data into obj and fails if there are unknown fields
+func decodeStrict(data []byte, obj interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(obj)
+}
+
+// normalizeYAML converts the map[interface{}]interface{} objects returned by
+// yaml.v2 into map[string]interface{} so that they can be encoded as JSON.
+func normalizeYAML(obj interface{}) interface{} {
+	switch val := obj.(type) {
+	case map[interface{}]interface{}:
+		res := map[string]interface{}{}
+		for k, v := range val {
+			res[fmt.Sprintf("%v", k)] = normalizeYAML(v)
+		}
+		return res
+	case []interface{}:
+		for i, v := range val {
+			val[i] = normalizeYAML(v)
+		}
+		return val
+	default:
+		return val
+	}
+}
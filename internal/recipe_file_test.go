@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRecipeFile(t *testing.T) {
+	recipe, err := LoadRecipeFile("../examples/l1-recipe.yaml")
+	if err != nil {
+		t.Fatalf("failed to load the example recipe: %v", err)
+	}
+	if recipe.Name() != "l1-file" || len(recipe.ServicesConfig) != 4 {
+		t.Fatalf("unexpected recipe %s with %d services", recipe.Name(), len(recipe.ServicesConfig))
+	}
+
+	// the params are decoded into a new instance of the component
+	beacon, ok := recipe.components[1].(*LighthouseBeaconNode)
+	if !ok || beacon.ExecutionNode != "el" || beacon.MevBoostNode != "mev-boost" {
+		t.Fatalf("unexpected beacon component %+v", recipe.components[1])
+	}
+	if beacon == FindComponent("lighthouse-beacon-node") {
+		t.Fatal("expected a new instance of the component")
+	}
+
+	manifest := recipe.Apply(&ExContext{}, &Artifacts{Out: &output{dst: t.TempDir()}})
+	if env := manifest.MustGetService("mev-boost").Env["EXTRA_ENV"]; env != "1" {
+		t.Fatalf("expected the env of the recipe file, got '%s'", env)
+	}
+
+	// the toml files are decoded into the same struct
+	dir := t.TempDir()
+	tomlRecipe := `
+name = "l1-toml"
+
+[[services]]
+name = "el"
+component = "reth"
+args = ["--log.stdout.format", "json"]
+
+[[services.ports]]
+name = "extra"
+port = 9999
+protocol = "udp"
+
+[[services]]
+name = "beacon"
+component = "lighthouse-beacon-node"
+params = { ExecutionNode = "el" }
+depends_on = [{ name = "el", condition = "service_healthy" }]
+`
+	path := filepath.Join(dir, "recipe.toml")
+	if err := os.WriteFile(path, []byte(tomlRecipe), 0644); err != nil {
+		t.Fatal(err)
+	}
+	recipe, err = LoadRecipeFile(path)
+	if err != nil {
+		t.Fatalf("failed to load the toml recipe: %v", err)
+	}
+	manifest = recipe.Apply(&ExContext{}, &Artifacts{Out: &output{dst: t.TempDir()}})
+	el := manifest.MustGetService("el")
+	if port := el.MustGetPort("extra"); port.Port != 9999 || port.Protocol != ProtocolUDP {
+		t.Fatalf("unexpected port %+v", port)
+	}
+	if !strings.Contains(strings.Join(el.Args, " "), "--log.stdout.format json") {
+		t.Fatalf("expected the args of the recipe file, got %v", el.Args)
+	}
+	deps := manifest.MustGetService("beacon").DependsOn
+	if len(deps) == 0 || deps[len(deps)-1] != (DependsOn{Name: "el", Condition: DependsOnConditionHealthy}) {
+		t.Fatalf("unexpected dependencies %v", deps)
+	}
+}
+
+func TestLoadRecipeFileInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	cases := map[string]struct {
+		file    string
+		content string
+		err     string
+	}{
+		"malformed yaml":    {"recipe.yaml", "name: [abc", "failed to decode yaml"},
+		"malformed toml":    {"recipe.toml", "name = ", "failed to decode toml"},
+		"extension":         {"recipe.json", "{}", "unsupported recipe file extension"},
+		"unknown field":     {"recipe.yaml", "name: a\nservice: []", "unknown field"},
+		"no name":           {"recipe.yaml", "services: [{name: el, component: reth}]", "does not have a name"},
+		"no services":       {"recipe.yaml", "name: a", "does not have any services"},
+		"unknown component": {"recipe.yaml", "name: a\nservices: [{name: el, component: abc}]", "component 'abc' not found"},
+		"duplicated":        {"recipe.yaml", "name: a\nservices: [{name: el, component: reth}, {name: el, component: reth}]", "defined twice"},
+		"unknown param":     {"recipe.yaml", "name: a\nservices: [{name: el, component: reth, params: {Abc: 1}}]", "failed to decode params"},
+		"protocol":          {"recipe.yaml", "name: a\nservices: [{name: el, component: reth, ports: [{name: p, port: 1, protocol: sctp}]}]", "invalid protocol"},
+		"condition":         {"recipe.yaml", "name: a\nservices: [{name: el, component: reth, depends_on: [{name: b, condition: done}]}]", "invalid depends_on condition"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, c.file)
+			if err := os.WriteFile(path, []byte(c.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadRecipeFile(path)
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("expected an error with '%s', got %v", c.err, err)
+			}
+		})
+	}
+
+	if _, err := LoadRecipeFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
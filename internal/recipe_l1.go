@@ -23,6 +23,45 @@ type L1Recipe struct {
 	// will run on the host machine. This is useful if you want to bind to the Reth database and you
 	// are running a host machine (i.e Mac) that is differerent from the docker one (Linux)
 	useNativeReth bool
+
+	// validators is the number of validators in the genesis
+	validators int
+
+	// validatorClients is the number of validator clients the validator keys are split into
+	validatorClients int
+
+	// elClient is the execution client of the first node
+	elClient ELClient
+
+	// clClient is the consensus client of the first node and the validator clients
+	clClient CLClient
+
+	// withMevBoost runs the mev-boost sidecar between the beacon node and the relays
+	// instead of connecting the beacon node directly to the mev-boost-relay
+	withMevBoost bool
+
+	// mevBoostRelays are extra relay URLs for the mev-boost sidecar
+	mevBoostRelays []string
+
+	// mevBoostMinBid is the minimum bid (in ETH) accepted by the mev-boost sidecar
+	mevBoostMinBid float64
+
+	// withBuilder runs the rbuilder block builder that submits blocks to the mev-boost-relay
+	withBuilder bool
+
+	// withSpammer runs the tx spammer against the el node
+	withSpammer bool
+
+	// spammerTPS and spammerTxTypes configure the load of the tx spammer
+	spammerTPS     uint64
+	spammerTxTypes []string
+
+	// spammerBundles sends the spammer transactions as bundles to the rbuilder
+	spammerBundles bool
+
+	// l1Nodes is the number of execution and beacon node pairs. The extra nodes
+	// do not have validators and peer with the first pair.
+	l1Nodes int
 }
 
 func (l *L1Recipe) Name() string {
@@ -39,23 +78,83 @@ func (l *L1Recipe) Flags() *flag.FlagSet {
 	flags.BoolVar(&l.useRethForValidation, "use-reth-for-validation", false, "use reth for validation")
 	flags.Uint64Var(&l.secondaryELPort, "secondary-el", 0, "port to use for the secondary builder")
 	flags.BoolVar(&l.useNativeReth, "use-native-reth", false, "use the native reth binary")
+	flags.IntVar(&l.validators, "validators", defaultValidatorCount, "number of validators in the genesis")
+	flags.IntVar(&l.validatorClients, "validator-clients", 1, "number of validator clients to split the validator keys across")
+	flags.BoolVar(&l.withBuilder, "with-builder", false, "run the rbuilder block builder on top of the reth el node")
+	flags.BoolVar(&l.withSpammer, "with-spammer", false, "run the tx spammer against the el node")
+	flags.Uint64Var(&l.spammerTPS, "spammer-tps", 10, "transactions per second sent by the tx spammer")
+	l.spammerTxTypes = []string{"dynamic"}
+	flags.Var(txTypesValue{&l.spammerTxTypes}, "spammer-tx-types", "transaction types sent by the tx spammer (legacy, dynamic, blob, call)")
+	flags.BoolVar(&l.spammerBundles, "spammer-bundles", false, "send the tx spammer transactions as bundles to the rbuilder (requires --with-builder)")
+	flags.IntVar(&l.l1Nodes, "l1-nodes", 1, "number of execution and beacon node pairs")
+	l.elClient = ELClientReth
+	flags.Var(&l.elClient, "el-client", "execution client to use (reth, geth or nethermind)")
+	l.clClient = CLClientLighthouse
+	flags.Var(&l.clClient, "cl-client", "consensus client to use (lighthouse, prysm or teku)")
+	flags.BoolVar(&l.withMevBoost, "with-mev-boost", false, "run the mev-boost sidecar between the beacon node and the relays")
+	flags.StringSliceVar(&l.mevBoostRelays, "mev-boost-relays", nil, "extra relay URLs (http://0xpubkey@host:port) for the mev-boost sidecar")
+	flags.Float64Var(&l.mevBoostMinBid, "mev-boost-min-bid", 0, "minimum bid in ETH accepted by the mev-boost sidecar")
 	return flags
 }
 
+var _ RecipeValidator = &L1Recipe{}
+
+func (l *L1Recipe) Validate() error {
+	if l.spammerBundles && !l.withBuilder {
+		return fmt.Errorf("--spammer-bundles requires --with-builder, the bundles are sent to the rbuilder")
+	}
+	if l.elClient != ELClientReth && (l.useRethForValidation || l.useNativeReth) {
+		return fmt.Errorf("--use-reth-for-validation and --use-native-reth require --el-client reth, got %s", l.elClient)
+	}
+	if l.l1Nodes < 1 {
+		return fmt.Errorf("--l1-nodes must be at least 1, got %d", l.l1Nodes)
+	}
+	if l.l1Nodes > 1 && l.clClient != CLClientLighthouse {
+		// the extra beacon nodes peer with the enr of the first lighthouse node
+		return fmt.Errorf("--l1-nodes requires --cl-client lighthouse, got %s", l.clClient)
+	}
+	if l.withBuilder {
+		// rbuilder reads the datadir and the IPC of the reth node through a docker volume
+		if l.elClient != ELClientReth {
+			return fmt.Errorf("--with-builder requires --el-client reth, got %s", l.elClient)
+		}
+		if l.useNativeReth {
+			return fmt.Errorf("--with-builder cannot be used with --use-native-reth, rbuilder needs the reth datadir in docker")
+		}
+	}
+	return nil
+}
+
 func (l *L1Recipe) Artifacts() *ArtifactsBuilder {
 	builder := NewArtifactsBuilder()
 	builder.ApplyLatestL1Fork(l.latestFork)
-
+	builder.Validators(l.validators, l.validatorClients)
+	builder.ConsensusClient(l.clClient)
 	return builder
 }
 
 func (l *L1Recipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
 	svcManager := NewManifest(ctx, artifacts.Out)
 
-	svcManager.AddService("el", &RethEL{
-		UseRethForValidation: l.useRethForValidation,
-		UseNativeReth:        l.useNativeReth,
-	})
+	var targetPeers uint64
+	if l.l1Nodes > 1 {
+		targetPeers = uint64(l.l1Nodes - 1)
+	}
+
+	switch l.elClient {
+	case ELClientGeth:
+		svcManager.AddService("el", &GethEL{
+			Bootnode: l.l1Nodes > 1,
+		})
+	case ELClientNethermind:
+		svcManager.AddService("el", &NethermindEL{})
+	default:
+		svcManager.AddService("el", &RethEL{
+			UseRethForValidation: l.useRethForValidation,
+			UseNativeReth:        l.useNativeReth,
+			Bootnode:             l.l1Nodes > 1,
+		})
+	}
 
 	var elService string
 	if l.secondaryELPort != 0 {
@@ -70,13 +169,47 @@ func (l *L1Recipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
 		elService = "el"
 	}
 
-	svcManager.AddService("beacon", &LighthouseBeaconNode{
-		ExecutionNode: elService,
-		MevBoostNode:  "mev-boost",
-	})
-	svcManager.AddService("validator", &LighthouseValidator{
-		BeaconNode: "beacon",
-	})
+	// the beacon node either talks to the relay directly or through the mev-boost sidecar
+	builderService := "mev-boost"
+	if l.withMevBoost {
+		builderService = "mev-boost-sidecar"
+		svcManager.AddService("mev-boost-sidecar", &MevBoost{
+			Relays:             append([]string{"mev-boost"}, l.mevBoostRelays...),
+			MinBid:             l.mevBoostMinBid,
+			GenesisTime:        artifacts.GenesisTime,
+			GenesisForkVersion: artifacts.GenesisForkVersion,
+		})
+	}
+
+	beacon := newBeaconNode(l.clClient, elService, builderService)
+	if lighthouse, ok := beacon.(*LighthouseBeaconNode); ok {
+		lighthouse.TargetPeers = targetPeers
+	}
+	svcManager.AddService("beacon", beacon)
+	if l.validatorClients <= 1 {
+		svcManager.AddService("validator", newValidatorClient(l.clClient, "beacon", ""))
+	} else {
+		for i := 0; i < l.validatorClients; i++ {
+			svcManager.AddService(fmt.Sprintf("validator-%d", i), newValidatorClient(l.clClient, "beacon", validatorKeystoreName(i, l.validatorClients)))
+		}
+	}
+
+	// the extra nodes follow the chain of the first pair
+	for i := 1; i < l.l1Nodes; i++ {
+		elName, beaconName := fmt.Sprintf("el-%d", i), fmt.Sprintf("beacon-%d", i)
+		follower := &RethEL{}
+		if l.elClient != ELClientNethermind {
+			// nethermind is not a bootnode, the execution nodes still follow
+			// the chain through the payloads of their beacon nodes
+			follower.TrustedPeer = "el"
+		}
+		svcManager.AddService(elName, follower)
+		svcManager.AddService(beaconName, &LighthouseBeaconNode{
+			ExecutionNode: elName,
+			Bootnode:      "beacon",
+			TargetPeers:   targetPeers,
+		})
+	}
 
 	mevBoostValidationServer := ""
 	if l.useRethForValidation {
@@ -85,10 +218,86 @@ func (l *L1Recipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
 	svcManager.AddService("mev-boost", &MevBoostRelay{
 		BeaconClient:     "beacon",
 		ValidationServer: mevBoostValidationServer,
+		ExecutionNode:    "el",
 	})
+
+	if l.withBuilder {
+		svcManager.AddService("rbuilder", &Rbuilder{
+			ExecutionNode: "el",
+			BeaconNode:    "beacon",
+			Relay:         "mev-boost",
+		})
+	}
+
+	if l.withSpammer {
+		spammer := &TxSpammer{
+			ELNode:  "el",
+			TPS:     l.spammerTPS,
+			TxTypes: l.spammerTxTypes,
+		}
+		if l.spammerBundles {
+			spammer.BundleNode = "rbuilder"
+		}
+		svcManager.AddService("tx-spammer", spammer)
+	}
 	return svcManager
 }
 
 func (l *L1Recipe) Output(manifest *Manifest) map[string]interface{} {
-	return map[string]interface{}{}
+	output := prefundedAccountsOutput(manifest)
+	if l.l1Nodes <= 1 {
+		return output
+	}
+
+	// the enode and enr of each node are captured once they are ready
+	for _, svc := range manifest.Services() {
+		switch component := svc.component.(type) {
+		case *RethEL:
+			if component.Enode != "" {
+				output[svc.Name+"-enode"] = component.Enode
+			}
+		case *LighthouseBeaconNode:
+			if component.ENR != "" {
+				output[svc.Name+"-enr"] = component.ENR
+			}
+		}
+	}
+	return output
+}
+
+// newL1ExecutionClient returns the component of the execution client with the default options
+func newL1ExecutionClient(client ELClient) ServiceGen {
+	switch client {
+	case ELClientGeth:
+		return &GethEL{}
+	case ELClientNethermind:
+		return &NethermindEL{}
+	default:
+		return &RethEL{}
+	}
+}
+
+// newBeaconNode returns the beacon node component of the consensus client
+func newBeaconNode(client CLClient, executionNode string, mevBoostNode string) ServiceGen {
+	switch client {
+	case CLClientPrysm:
+		return &PrysmBeaconNode{ExecutionNode: executionNode, MevBoostNode: mevBoostNode}
+	case CLClientTeku:
+		return &TekuBeaconNode{ExecutionNode: executionNode, MevBoostNode: mevBoostNode}
+	default:
+		return &LighthouseBeaconNode{ExecutionNode: executionNode, MevBoostNode: mevBoostNode}
+	}
+}
+
+// newValidatorClient returns the validator client component of the consensus client.
+// The keystore artifact must be written with the layout of the same client (see ArtifactsBuilder.ConsensusClient).
+func newValidatorClient(client CLClient, beaconNode string, keystore string) ServiceGen {
+	switch client {
+	case CLClientPrysm:
+		return &PrysmValidator{BeaconNode: beaconNode, Keystore: keystore}
+	case CLClientTeku:
+		return &TekuValidator{BeaconNode: beaconNode, Keystore: keystore}
+	default:
+		return &LighthouseValidator{BeaconNode: beaconNode, Keystore: keystore}
+	}
 }
@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/flashbots/go-boost-utils/bls"
+	mevboostrelay "github.com/phylaxsystems/builder-playground/mev-boost-relay"
+)
+
+// applyL1Recipe applies the l1 recipe with the flags to fake artifacts
+func applyL1Recipe(t *testing.T, args ...string) *Manifest {
+	recipe := &L1Recipe{}
+	if err := recipe.Flags().Parse(args); err != nil {
+		t.Fatalf("failed to parse the flags: %v", err)
+	}
+	if err := recipe.Validate(); err != nil {
+		t.Fatalf("failed to validate the flags: %v", err)
+	}
+	return recipe.Apply(&ExContext{}, &Artifacts{Out: &output{dst: t.TempDir()}})
+}
+
+func TestL1RecipeValidate(t *testing.T) {
+	cases := []struct {
+		args  []string
+		valid bool
+	}{
+		{[]string{"--with-spammer"}, true},
+		{[]string{"--with-spammer", "--spammer-bundles", "--with-builder"}, true},
+		{[]string{"--with-spammer", "--spammer-bundles"}, false},
+		{[]string{"--with-builder", "--el-client", "reth"}, true},
+		{[]string{"--with-builder", "--el-client", "geth"}, false},
+		{[]string{"--with-builder", "--el-client", "nethermind"}, false},
+		{[]string{"--with-builder", "--use-native-reth"}, false},
+		{[]string{"--use-reth-for-validation", "--el-client", "reth"}, true},
+		{[]string{"--use-reth-for-validation", "--el-client", "geth"}, false},
+		{[]string{"--use-native-reth", "--el-client", "nethermind"}, false},
+		{[]string{"--l1-nodes", "2", "--cl-client", "lighthouse"}, true},
+		{[]string{"--l1-nodes", "2", "--cl-client", "prysm"}, false},
+		{[]string{"--l1-nodes", "2", "--cl-client", "teku"}, false},
+		{[]string{"--cl-client", "teku"}, true},
+		{[]string{"--l1-nodes", "0"}, false},
+		{[]string{"--l1-nodes", "-1"}, false},
+	}
+	for _, c := range cases {
+		recipe := &L1Recipe{}
+		if err := recipe.Flags().Parse(c.args); err != nil {
+			t.Fatal(err)
+		}
+		if err := recipe.Validate(); (err == nil) != c.valid {
+			t.Fatalf("unexpected validation of %v: %v", c.args, err)
+		}
+	}
+}
+
+func TestRbuilderConfig(t *testing.T) {
+	manifest := applyL1Recipe(t, "--with-builder")
+	if err := manifest.WriteConfigs(); err != nil {
+		t.Fatalf("failed to write the configs: %v", err)
+	}
+
+	var config struct {
+		ClNodeURL         []string `toml:"cl_node_url"`
+		RelaySecretKey    string   `toml:"relay_secret_key"`
+		CoinbaseSecretKey string   `toml:"coinbase_secret_key"`
+		Relays            []struct {
+			URL string `toml:"url"`
+		} `toml:"relays"`
+	}
+	if _, err := toml.DecodeFile(filepath.Join(manifest.out.dst, "rbuilder.toml"), &config); err != nil {
+		t.Fatalf("failed to decode the rbuilder config: %v", err)
+	}
+
+	// the urls use the names and the ports of the services in the manifest
+	relayPort := manifest.MustGetService("mev-boost").MustGetPort("http").Port
+	beaconPort := manifest.MustGetService("beacon").MustGetPort("http").Port
+	if len(config.Relays) != 1 || config.Relays[0].URL != fmt.Sprintf("http://%s@mev-boost:%d", mevBoostRelayPubKey, relayPort) {
+		t.Fatalf("unexpected relays %+v", config.Relays)
+	}
+	if len(config.ClNodeURL) != 1 || config.ClNodeURL[0] != fmt.Sprintf("http://beacon:%d", beaconPort) {
+		t.Fatalf("unexpected beacon node %v", config.ClNodeURL)
+	}
+	if "0x"+config.CoinbaseSecretKey != prefundedAccounts[rbuilderCoinbaseAccount] {
+		t.Fatalf("unexpected coinbase key %s", config.CoinbaseSecretKey)
+	}
+
+	// the rbuilder reads the database of the reth node, its version has to match the one of reth
+	if tag := manifest.MustGetService("rbuilder").Tag; tag == "" || tag == "latest" {
+		t.Fatalf("expected a pinned rbuilder image, got '%s'", tag)
+	}
+
+	// the builder signs the submissions with its own key, not with the one of the relay
+	if config.RelaySecretKey == mevboostrelay.DefaultSecretKey {
+		t.Fatal("the rbuilder uses the secret key of the relay")
+	}
+	sk, err := hex.DecodeString(config.RelaySecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bls.SecretKeyFromBytes(sk); err != nil {
+		t.Fatalf("invalid rbuilder secret key: %v", err)
+	}
+
+	// the config is not written without the builder
+	manifest = applyL1Recipe(t)
+	if err := manifest.WriteConfigs(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(manifest.out.dst, "rbuilder.toml")); !os.IsNotExist(err) {
+		t.Fatalf("expected no rbuilder config, got %v", err)
+	}
+}
+
+func TestL1NodesPeering(t *testing.T) {
+	manifest := applyL1Recipe(t, "--l1-nodes", "2")
+
+	// fake the execution and beacon nodes, the extra nodes record the peers they are asked to add
+	var mu sync.Mutex
+	peers := map[string][]string{}
+	addPeer := func(name, peer string) {
+		mu.Lock()
+		defer mu.Unlock()
+		peers[name] = append(peers[name], peer)
+	}
+	servers := map[string]*httptest.Server{}
+	for i, name := range []string{"el", "el-1"} {
+		servers[name] = jsonRPCServer(t, func(method string, params []interface{}) interface{} {
+			switch method {
+			case "admin_nodeInfo":
+				return map[string]string{"enode": fmt.Sprintf("enode://%0128x@0.0.0.0:30303", i+1)}
+			case "admin_addTrustedPeer", "admin_addPeer":
+				addPeer(name, method+" "+params[0].(string))
+				return true
+			}
+			return nil
+		})
+	}
+	for _, name := range []string{"beacon", "beacon-1"} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/eth/v1/node/identity":
+				json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"enr": "enr:" + name}})
+			case "/lighthouse/add_peer":
+				var req struct {
+					ENR string `json:"enr"`
+				}
+				json.NewDecoder(r.Body).Decode(&req)
+				addPeer(name, req.ENR)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(srv.Close)
+		servers[name] = srv
+	}
+
+	instances, err := newInstances(&output{dst: t.TempDir()}, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nodes []*instance
+	ips := map[string]string{}
+	for i, instance := range instances {
+		srv, ok := servers[instance.service.Name]
+		if !ok {
+			continue
+		}
+		u, err := url.Parse(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		port, _ := strconv.Atoi(u.Port())
+		instance.service.MustGetPort("http").HostPort = port
+		instance.ip = fmt.Sprintf("10.0.0.%d", i+1)
+		ips[instance.service.Name] = instance.ip
+		nodes = append(nodes, instance)
+	}
+	if err := CompleteReady(nodes); err != nil {
+		t.Fatalf("failed to complete the ready hooks: %v", err)
+	}
+
+	// only the address of the first execution node is resolved by the runner
+	for _, svc := range manifest.Services() {
+		if isELBootnode(svc.component) != (svc.Name == "el") {
+			t.Fatalf("unexpected bootnode %s", svc.Name)
+		}
+	}
+	if isELBootnode(applyL1Recipe(t).MustGetService("el").component) {
+		t.Fatal("expected no bootnode in a single node setup")
+	}
+
+	// the bootnodes advertise their address in the network
+	if !slices.Contains(manifest.MustGetService("beacon").Args, "beacon") {
+		t.Fatalf("expected the enr address of the bootnode to be its service name, got %v", manifest.MustGetService("beacon").Args)
+	}
+	elEnode := fmt.Sprintf("enode://%0128x@%s:30303", 1, ips["el"])
+	expected := map[string][]string{
+		"el-1":     {"admin_addTrustedPeer " + elEnode, "admin_addPeer " + elEnode},
+		"beacon-1": {"enr:beacon"},
+	}
+	if !reflect.DeepEqual(peers, expected) {
+		t.Fatalf("unexpected peers %v, expected %v", peers, expected)
+	}
+}
+
+func TestNethermindDiscoveryPort(t *testing.T) {
+	manifest := applyL1Recipe(t, "--el-client", "nethermind")
+
+	// the discovery runs on the udp port with the same number as the p2p port
+	protocols := map[string]bool{}
+	for _, port := range manifest.MustGetService("el").Ports {
+		if port.Name == "rpc" {
+			protocols[port.Protocol] = true
+		}
+	}
+	if !protocols[ProtocolTCP] || !protocols[ProtocolUDP] {
+		t.Fatalf("expected the tcp and udp rpc ports, got %v", protocols)
+	}
+}
@@ -1,6 +1,11 @@
 package internal
 
 import (
+	"fmt"
+	"slices"
+	"time"
+
+	txspammer "github.com/phylaxsystems/builder-playground/tx-spammer"
 	flag "github.com/spf13/pflag"
 )
 
@@ -22,6 +27,48 @@ type OpRecipe struct {
 	// batcherMaxChannelDuration is the maximum channel duration to use for the batcher
 	// (default is 2 seconds)
 	batcherMaxChannelDuration uint64
+
+	// elClient is the execution client of the L1 chain
+	elClient ELClient
+
+	// clClient is the consensus client of the L1 chain
+	clClient CLClient
+
+	// proposalInterval is the interval between the output roots posted by the op-proposer
+	proposalInterval time.Duration
+
+	// withProposer runs the op-proposer to post the output roots to L1
+	withProposer bool
+
+	// withChallenger runs the op-challenger to play the dispute games of the op-proposer,
+	// it implies withProposer
+	withChallenger bool
+
+	// challengerPrestatesURL is the base URL of the op-program prestates of the op-challenger
+	challengerPrestatesURL string
+
+	// withSpammer runs the tx spammer against the spammerTarget chain (l1 or l2)
+	withSpammer   bool
+	spammerTarget string
+
+	// spammerTPS and spammerTxTypes configure the load of the tx spammer
+	spammerTPS     uint64
+	spammerTxTypes []string
+
+	// opDeployer is the op-deployer binary or container image used to deploy the L2 contracts
+	opDeployer string
+
+	// l2ParamsFile and l2Params are the params of the L2 chain (chain id, gas limit, fees)
+	l2ParamsFile string
+	l2Params     OpChainParams
+
+	// l2Chains is the number of L2 chains that settle on the same L1. The extra chains
+	// only run the sequencer (op-node-N, op-geth-N and op-batcher-N).
+	l2Chains int
+
+	// flashblocks runs op-rbuilder with flashblocks behind rollup-boost and
+	// the websocket proxy of the flashblocks stream
+	flashblocks bool
 }
 
 func (o *OpRecipe) Name() string {
@@ -38,42 +85,111 @@ func (o *OpRecipe) Flags() *flag.FlagSet {
 	flags.Var(&nullableUint64Value{&o.enableLatestFork}, "enable-latest-fork", "Enable latest fork isthmus (nil or empty = disabled, otherwise enabled at specified block)")
 	flags.Uint64Var(&o.blockTime, "block-time", defaultOpBlockTimeSeconds, "Block time to use for the rollup")
 	flags.Uint64Var(&o.batcherMaxChannelDuration, "batcher-max-channel-duration", 2, "Maximum channel duration to use for the batcher")
+	o.elClient = ELClientReth
+	flags.Var(&o.elClient, "el-client", "L1 execution client to use (reth, geth or nethermind)")
+	o.clClient = CLClientLighthouse
+	flags.Var(&o.clClient, "cl-client", "L1 consensus client to use (lighthouse, prysm or teku)")
+	flags.DurationVar(&o.proposalInterval, "proposal-interval", 12*time.Second, "interval between the output roots posted by the op-proposer")
+	flags.BoolVar(&o.withProposer, "with-proposer", false, "run the op-proposer")
+	flags.BoolVar(&o.withChallenger, "with-challenger", false, "run the op-challenger (implies --with-proposer)")
+	flags.StringVar(&o.challengerPrestatesURL, "challenger-prestates-url", defaultOpPrestatesURL, "base URL of the op-program prestates of the op-challenger")
+	flags.BoolVar(&o.withSpammer, "with-spammer", false, "run the tx spammer")
+	flags.StringVar(&o.spammerTarget, "spammer-target", "l2", "chain of the tx spammer (l1 or l2)")
+	flags.Uint64Var(&o.spammerTPS, "spammer-tps", 10, "transactions per second sent by the tx spammer")
+	o.spammerTxTypes = []string{"dynamic"}
+	flags.Var(txTypesValue{&o.spammerTxTypes}, "spammer-tx-types", "transaction types sent by the tx spammer (legacy, dynamic, blob, call)")
+	flags.StringVar(&o.opDeployer, "op-deployer", "", "op-deployer binary or container image to deploy the L2 contracts (defaults to the embedded deployment)")
+	opChainParamsFlags(flags, &o.l2ParamsFile, &o.l2Params)
+	flags.IntVar(&o.l2Chains, "l2-chains", 1, "number of L2 chains that settle on the L1")
+	flags.BoolVar(&o.flashblocks, "flashblocks", false, "build flashblocks with op-rbuilder and serve them with rollup-boost and the flashblocks-proxy")
 	return flags
 }
 
+var _ RecipeValidator = &OpRecipe{}
+
+func (o *OpRecipe) Validate() error {
+	if (o.withProposer || o.withChallenger) && o.opDeployer == "" {
+		// the permissioned dispute games of the embedded deployment only accept the
+		// proposer and the challenger of utils/state.json
+		return fmt.Errorf("--with-proposer and --with-challenger require --op-deployer")
+	}
+	if o.l2Chains > 1 && o.opDeployer == "" {
+		// the chains cannot share the portal and the system config of the embedded deployment
+		return fmt.Errorf("--l2-chains > 1 requires --op-deployer")
+	}
+	if o.opDeployer == "" && (o.l2Params.ChainID != 0 || o.l2Params.GasLimit != 0) {
+		// the L1 contracts of the embedded deployment keep their chain id and gas limit
+		return fmt.Errorf("--l2-chain-id and --l2-gas-limit require --op-deployer")
+	}
+	if o.flashblocks && o.externalBuilder != "" {
+		// the flashblocks are built by the op-rbuilder of the recipe
+		return fmt.Errorf("--flashblocks cannot be used with --external-builder")
+	}
+	if o.spammerTarget != "l1" && o.spammerTarget != "l2" {
+		return fmt.Errorf("invalid --spammer-target '%s', expected l1 or l2", o.spammerTarget)
+	}
+	if o.withSpammer && o.spammerTarget == "l2" && slices.Contains(o.spammerTxTypes, txspammer.TxTypeBlob) {
+		return fmt.Errorf("the L2 does not accept blob transactions, use --spammer-target l1 to send them")
+	}
+	return nil
+}
+
 func (o *OpRecipe) Artifacts() *ArtifactsBuilder {
 	builder := NewArtifactsBuilder()
 	builder.ApplyLatestL2Fork(o.enableLatestFork)
 	builder.OpBlockTime(o.blockTime)
+	builder.ConsensusClient(o.clClient)
+	builder.OpDeployer(o.opDeployer)
+	builder.OpProposer(o.withProposer || o.withChallenger)
+	builder.OpChainParams(o.l2ParamsFile, &o.l2Params)
+	builder.L2Chains(o.l2Chains)
 	return builder
 }
 
 func (o *OpRecipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
 	svcManager := NewManifest(ctx, artifacts.Out)
-	svcManager.AddService("el", &RethEL{})
-	svcManager.AddService("beacon", &LighthouseBeaconNode{
-		ExecutionNode: "el",
-	})
-	svcManager.AddService("validator", &LighthouseValidator{
-		BeaconNode: "beacon",
-	})
+	svcManager.AddService("el", newL1ExecutionClient(o.elClient))
+	svcManager.AddService("beacon", newBeaconNode(o.clClient, "el", ""))
+	svcManager.AddService("validator", newValidatorClient(o.clClient, "beacon", ""))
 
-	externalBuilderRef := o.externalBuilder
-	if o.externalBuilder == "op-reth" {
+	externalBuilder := o.externalBuilder
+	if o.flashblocks {
+		// the flashblocks are built by op-rbuilder and relayed by rollup-boost (Validate
+		// rejects --flashblocks with --external-builder)
+		externalBuilder = "op-rbuilder"
+	}
+
+	externalBuilderRef := externalBuilder
+	if externalBuilder == "op-reth" {
 		// Add a new op-reth service and connect it to Rollup-boost
 		svcManager.AddService("op-reth", &OpReth{})
 
 		externalBuilderRef = Connect("op-reth", "authrpc")
+	} else if externalBuilder == "op-rbuilder" {
+		svcManager.AddService("op-rbuilder", &OpRbuilder{
+			Flashblocks: o.flashblocks,
+			BlockTime:   o.blockTime,
+		})
+
+		externalBuilderRef = Connect("op-rbuilder", "authrpc")
 	}
 
 	elNode := "op-geth"
-	if o.externalBuilder != "" {
+	if externalBuilder != "" {
 		elNode = "rollup-boost"
 
-		svcManager.AddService("rollup-boost", &RollupBoost{
+		rollupBoost := &RollupBoost{
 			ELNode:  "op-geth",
 			Builder: externalBuilderRef,
-		})
+		}
+		if o.flashblocks {
+			rollupBoost.FlashblocksBuilder = "ws://" + ConnectRaw("op-rbuilder", "flashblocks", "")
+			svcManager.AddService("flashblocks-proxy", &FlashblocksProxy{
+				Upstream:  "rollup-boost",
+				BlockTime: o.blockTime,
+			})
+		}
+		svcManager.AddService("rollup-boost", rollupBoost)
 	}
 	svcManager.AddService("op-node", &OpNode{
 		L1Node:   "el",
@@ -81,7 +197,7 @@ func (o *OpRecipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
 		L2Node:   elNode,
 	})
 	svcManager.AddService("op-geth", &OpGeth{
-		UseDeterministicP2PKey: o.externalBuilder != "",
+		UseDeterministicP2PKey: externalBuilder != "",
 	})
 	svcManager.AddService("op-batcher", &OpBatcher{
 		L1Node:             "el",
@@ -89,6 +205,68 @@ func (o *OpRecipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
 		RollupNode:         "op-node",
 		MaxChannelDuration: o.batcherMaxChannelDuration,
 	})
+	if o.withProposer || o.withChallenger {
+		svcManager.AddService("op-proposer", &OpProposer{
+			L1Node:             "el",
+			RollupNode:         "op-node",
+			DisputeGameFactory: artifacts.DisputeGameFactory.Hex(),
+			ProposalInterval:   o.proposalInterval,
+		})
+	}
+
+	// the extra chains share the L1 nodes with the first one
+	for i := 1; i < o.l2Chains; i++ {
+		opNode, opGeth, opBatcher := fmt.Sprintf("op-node-%d", i), fmt.Sprintf("op-geth-%d", i), fmt.Sprintf("op-batcher-%d", i)
+		genesis, rollupConfig := opChainArtifacts(i)
+		svcManager.AddService(opNode, &OpNode{
+			L1Node:       "el",
+			L1Beacon:     "beacon",
+			L2Node:       opGeth,
+			RollupConfig: rollupConfig,
+		})
+		svcManager.AddService(opGeth, &OpGeth{
+			Genesis: genesis,
+		})
+		svcManager.AddService(opBatcher, &OpBatcher{
+			L1Node:             "el",
+			L2Node:             opGeth,
+			RollupNode:         opNode,
+			MaxChannelDuration: o.batcherMaxChannelDuration,
+			PrivateKey:         opBatcherPrivateKey(i),
+		})
+	}
+
+	if o.withChallenger {
+		svcManager.AddService("op-challenger", &OpChallenger{
+			L1Node:             "el",
+			L1Beacon:           "beacon",
+			L2Node:             "op-geth",
+			RollupNode:         "op-node",
+			DisputeGameFactory: artifacts.DisputeGameFactory.Hex(),
+			PrestatesURL:       o.challengerPrestatesURL,
+		})
+	}
+
+	if o.withSpammer {
+		spammerNode := "op-geth"
+		if o.spammerTarget == "l1" {
+			spammerNode = "el"
+		}
+		svcManager.AddService("tx-spammer", &TxSpammer{
+			ELNode:  spammerNode,
+			TPS:     o.spammerTPS,
+			TxTypes: o.spammerTxTypes,
+		})
+
+		// the L2 chains do not share the prefunded accounts, each one has its own spammer
+		for i := 1; i < o.l2Chains && o.spammerTarget != "l1"; i++ {
+			svcManager.AddService(fmt.Sprintf("tx-spammer-%d", i), &TxSpammer{
+				ELNode:  fmt.Sprintf("op-geth-%d", i),
+				TPS:     o.spammerTPS,
+				TxTypes: o.spammerTxTypes,
+			})
+		}
+	}
 	return svcManager
 }
 
@@ -102,5 +280,5 @@ func (o *OpRecipe) Output(manifest *Manifest) map[string]interface{} {
 			}
 		}
 	*/
-	return map[string]interface{}{}
+	return prefundedAccountsOutput(manifest)
 }
@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// applyOpRecipe applies the opstack recipe with the flags to fake artifacts
+func applyOpRecipe(t *testing.T, args ...string) (*OpRecipe, *Manifest) {
+	recipe := &OpRecipe{}
+	if err := recipe.Flags().Parse(args); err != nil {
+		t.Fatalf("failed to parse the flags: %v", err)
+	}
+	if err := recipe.Validate(); err != nil {
+		t.Fatalf("failed to validate the flags: %v", err)
+	}
+	artifacts := &Artifacts{
+		Out:                &output{dst: t.TempDir()},
+		DisputeGameFactory: gethcommon.HexToAddress("0xfebfe4661e58910a0612f951069482aba340c9e0"),
+	}
+	return recipe, recipe.Apply(&ExContext{}, artifacts)
+}
+
+// serviceArg returns the value of the --name=value argument of the service
+func serviceArg(svc *Service, name string) string {
+	for _, arg := range svc.Args {
+		if strings.HasPrefix(arg, name+"=") {
+			return strings.TrimPrefix(arg, name+"=")
+		}
+	}
+	return ""
+}
+
+func TestOpRecipeProposer(t *testing.T) {
+	_, manifest := applyOpRecipe(t)
+	for _, name := range []string{"op-proposer", "op-challenger"} {
+		if _, ok := manifest.GetService(name); ok {
+			t.Fatalf("expected no %s without the flags", name)
+		}
+	}
+
+	// the dispute games of the embedded deployment do not accept the proposer
+	for _, flag := range []string{"--with-proposer", "--with-challenger"} {
+		recipe := &OpRecipe{}
+		if err := recipe.Flags().Parse([]string{flag}); err != nil {
+			t.Fatal(err)
+		}
+		if err := recipe.Validate(); err == nil {
+			t.Fatalf("expected %s to require --op-deployer", flag)
+		}
+	}
+
+	// the challenger implies the proposer and each role has its own key
+	_, manifest = applyOpRecipe(t, "--with-challenger", "--op-deployer", "op-deployer")
+	keys := map[string]string{
+		"op-batcher":    opRolesPrivateKey,
+		"op-proposer":   opProposerPrivateKey,
+		"op-challenger": opChallengerPrivateKey,
+	}
+	for name, key := range keys {
+		svc, ok := manifest.GetService(name)
+		if !ok {
+			t.Fatalf("expected the %s service", name)
+		}
+		if got := serviceArg(svc, "--private-key"); got != key {
+			t.Fatalf("expected %s to use its own key, got %s", name, got)
+		}
+	}
+
+	// the dispute games are only watched once, by the proposer
+	if _, ok := manifest.MustGetService("op-challenger").component.(ServiceWatchdog); ok {
+		t.Fatal("expected no watchdog on the op-challenger")
+	}
+	if _, ok := manifest.MustGetService("op-proposer").component.(ServiceWatchdog); !ok {
+		t.Fatal("expected the dispute games watchdog on the op-proposer")
+	}
+}
+
+func TestOpRecipeL2Chains(t *testing.T) {
+	recipe := &OpRecipe{}
+	if err := recipe.Flags().Parse([]string{"--l2-chains", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recipe.Validate(); err == nil {
+		t.Fatal("expected --l2-chains to require --op-deployer")
+	}
+
+	// each L2 chain has its own spammer
+	_, manifest := applyOpRecipe(t, "--l2-chains", "2", "--op-deployer", "op-deployer", "--with-spammer")
+	for name, elNode := range map[string]string{"tx-spammer": "op-geth", "tx-spammer-1": "op-geth-1"} {
+		svc, ok := manifest.GetService(name)
+		if !ok {
+			t.Fatalf("expected the %s service", name)
+		}
+		if spammer := svc.component.(*TxSpammer); spammer.ELNode != elNode {
+			t.Fatalf("expected %s to target %s, got %s", name, elNode, spammer.ELNode)
+		}
+	}
+}
+
+func TestOpRecipesL2Params(t *testing.T) {
+	cases := []struct {
+		args  []string
+		valid bool
+	}{
+		{[]string{"--l2-eip1559-elasticity", "4", "--l2-sequencer-fee-vault-recipient", "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"}, true},
+		{[]string{"--l2-chain-id", "4242"}, false},
+		{[]string{"--l2-gas-limit", "90000000"}, false},
+		{[]string{"--l2-chain-id", "4242", "--l2-gas-limit", "90000000", "--op-deployer", "op-deployer"}, true},
+	}
+	for _, c := range cases {
+		// the chain id and the gas limit are part of the L1 contracts of the embedded deployment
+		for _, recipe := range []Recipe{&OpRecipe{}, &OpTalosRecipe{}} {
+			if err := recipe.Flags().Parse(c.args); err != nil {
+				t.Fatal(err)
+			}
+			if err := recipe.(RecipeValidator).Validate(); (err == nil) != c.valid {
+				t.Fatalf("unexpected validation of %v in the %s recipe: %v", c.args, recipe.Name(), err)
+			}
+		}
+	}
+}
+
+func TestOpRecipeSpammerTarget(t *testing.T) {
+	cases := []struct {
+		args  []string
+		valid bool
+	}{
+		{[]string{"--with-spammer"}, true},
+		{[]string{"--with-spammer", "--spammer-target", "l1", "--spammer-tx-types", "blob,dynamic"}, true},
+		{[]string{"--with-spammer", "--spammer-target", "L1"}, false},
+		{[]string{"--with-spammer", "--spammer-target", "op-geth"}, false},
+		{[]string{"--with-spammer", "--spammer-tx-types", "dynamic,blob"}, false},
+	}
+	for _, c := range cases {
+		recipe := &OpRecipe{}
+		if err := recipe.Flags().Parse(c.args); err != nil {
+			t.Fatal(err)
+		}
+		if err := recipe.Validate(); (err == nil) != c.valid {
+			t.Fatalf("unexpected validation of %v: %v", c.args, err)
+		}
+	}
+}
+
+func TestOpRecipeFlashblocks(t *testing.T) {
+	_, manifest := applyOpRecipe(t, "--flashblocks")
+	for _, name := range []string{"op-rbuilder", "rollup-boost", "flashblocks-proxy"} {
+		if _, ok := manifest.GetService(name); !ok {
+			t.Fatalf("expected the %s service with --flashblocks", name)
+		}
+	}
+	// the flashblocks flags depend on the version of op-rbuilder
+	if tag := manifest.MustGetService("op-rbuilder").Tag; tag == "" || tag == "latest" {
+		t.Fatalf("expected a pinned op-rbuilder image, got '%s'", tag)
+	}
+
+	// the flashblocks are built by op-rbuilder, not by the external builder
+	for _, recipe := range []Recipe{&OpRecipe{}, &OpTalosRecipe{}} {
+		if err := recipe.Flags().Parse([]string{"--flashblocks", "--external-builder", "http://localhost:8551"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := recipe.(RecipeValidator).Validate(); err == nil || !strings.Contains(err.Error(), "--external-builder") {
+			t.Fatalf("expected %s to reject --flashblocks with --external-builder, got %v", recipe.Name(), err)
+		}
+	}
+}
+
+func TestOpRecipesL1Clients(t *testing.T) {
+	for _, recipe := range []Recipe{&OpRecipe{}, &OpTalosRecipe{}} {
+		if err := recipe.Flags().Parse([]string{"--el-client", "geth", "--cl-client", "teku"}); err != nil {
+			t.Fatal(err)
+		}
+		artifacts := &Artifacts{
+			Out:                &output{dst: t.TempDir()},
+			DisputeGameFactory: gethcommon.HexToAddress("0xfebfe4661e58910a0612f951069482aba340c9e0"),
+		}
+		manifest := recipe.Apply(&ExContext{}, artifacts)
+		if _, ok := manifest.MustGetService("el").component.(*GethEL); !ok {
+			t.Fatalf("expected the geth execution client in the %s recipe", recipe.Name())
+		}
+		if _, ok := manifest.MustGetService("beacon").component.(*TekuBeaconNode); !ok {
+			t.Fatalf("expected the teku beacon node in the %s recipe", recipe.Name())
+		}
+		if _, ok := manifest.MustGetService("validator").component.(*TekuValidator); !ok {
+			t.Fatalf("expected the teku validator in the %s recipe", recipe.Name())
+		}
+	}
+}
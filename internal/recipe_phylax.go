@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"fmt"
+
 	flag "github.com/spf13/pflag"
 )
 
@@ -35,6 +37,23 @@ type OpTalosRecipe struct {
 
 	// faucet is the private key of the faucet address
 	faucet bool
+
+	// opDeployer is the op-deployer binary or container image used to deploy the L2 contracts
+	opDeployer string
+
+	// l2ParamsFile and l2Params are the params of the L2 chain (chain id, gas limit, fees)
+	l2ParamsFile string
+	l2Params     OpChainParams
+
+	// flashblocks enables the flashblocks of op-talos, relayed by rollup-boost
+	// and served by the websocket proxy
+	flashblocks bool
+
+	// elClient is the execution client of the L1 chain
+	elClient ELClient
+
+	// clClient is the consensus client of the L1 chain
+	clClient CLClient
 }
 
 func (o *OpTalosRecipe) Name() string {
@@ -55,25 +74,45 @@ func (o *OpTalosRecipe) Flags() *flag.FlagSet {
 	flags.Uint64Var(&o.assexGasLimit, "assex-gas-limit", 30000000, "Gas limit of the Assertion Execution")
 	flags.StringVar(&o.oracleContract, "oracle-contract", "0x6dD3f12ce435f69DCeDA7e31605C02Bb5422597b", "State Oracle contract address")
 	flags.BoolVar(&o.faucet, "faucet", false, "Enable the faucet")
+	flags.StringVar(&o.opDeployer, "op-deployer", "", "op-deployer binary or container image to deploy the L2 contracts (defaults to the embedded deployment)")
+	opChainParamsFlags(flags, &o.l2ParamsFile, &o.l2Params)
+	flags.BoolVar(&o.flashblocks, "flashblocks", false, "build flashblocks with op-talos and serve them with rollup-boost and the flashblocks-proxy (requires the local op-talos)")
+	o.elClient = ELClientReth
+	flags.Var(&o.elClient, "el-client", "L1 execution client to use (reth, geth or nethermind)")
+	o.clClient = CLClientLighthouse
+	flags.Var(&o.clClient, "cl-client", "L1 consensus client to use (lighthouse, prysm or teku)")
 	return flags
 }
 
+var _ RecipeValidator = &OpTalosRecipe{}
+
+func (o *OpTalosRecipe) Validate() error {
+	if o.opDeployer == "" && (o.l2Params.ChainID != 0 || o.l2Params.GasLimit != 0) {
+		// the L1 contracts of the embedded deployment keep their chain id and gas limit
+		return fmt.Errorf("--l2-chain-id and --l2-gas-limit require --op-deployer")
+	}
+	if o.flashblocks && o.externalBuilder != "" {
+		// the flashblocks stream of an external builder is unknown
+		return fmt.Errorf("--flashblocks requires the local op-talos, it cannot be used with --external-builder")
+	}
+	return nil
+}
+
 func (o *OpTalosRecipe) Artifacts() *ArtifactsBuilder {
 	builder := NewArtifactsBuilder()
 	builder.ApplyLatestL2Fork(o.enableLatestFork)
 	builder.OpBlockTime(o.blockTime)
+	builder.OpDeployer(o.opDeployer)
+	builder.OpChainParams(o.l2ParamsFile, &o.l2Params)
+	builder.ConsensusClient(o.clClient)
 	return builder
 }
 
 func (o *OpTalosRecipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
 	svcManager := NewManifest(ctx, artifacts.Out)
-	svcManager.AddService("el", &RethEL{})
-	svcManager.AddService("beacon", &LighthouseBeaconNode{
-		ExecutionNode: "el",
-	})
-	svcManager.AddService("validator", &LighthouseValidator{
-		BeaconNode: "beacon",
-	})
+	svcManager.AddService("el", newL1ExecutionClient(o.elClient))
+	svcManager.AddService("beacon", newBeaconNode(o.clClient, "el", ""))
+	svcManager.AddService("validator", newValidatorClient(o.clClient, "beacon", ""))
 
 	externalDaRef := o.externalDA
 	if o.externalDA == "" || o.externalDA == "dev" {
@@ -93,6 +132,8 @@ func (o *OpTalosRecipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
 			AssertionDA:    externalDaRef,
 			AssexGasLimit:  o.assexGasLimit,
 			OracleContract: o.oracleContract,
+			Flashblocks:    o.flashblocks,
+			BlockTime:      o.blockTime,
 		})
 		externalBuilderRef = Connect("op-talos", "authrpc")
 	}
@@ -111,10 +152,18 @@ func (o *OpTalosRecipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
 
 	elNode := "rollup-boost"
 
-	svcManager.AddService("rollup-boost", &RollupBoost{
+	rollupBoost := &RollupBoost{
 		ELNode:  "op-geth",
 		Builder: externalBuilderRef,
-	})
+	}
+	if o.flashblocks {
+		rollupBoost.FlashblocksBuilder = "ws://" + ConnectRaw("op-talos", "flashblocks", "")
+		svcManager.AddService("flashblocks-proxy", &FlashblocksProxy{
+			Upstream:  "rollup-boost",
+			BlockTime: o.blockTime,
+		})
+	}
+	svcManager.AddService("rollup-boost", rollupBoost)
 
 	svcManager.AddService("op-node", &OpNode{
 		L1Node:   "el",
@@ -143,5 +192,5 @@ func (o *OpTalosRecipe) Output(manifest *Manifest) map[string]interface{} {
 			}
 		}
 	*/
-	return map[string]interface{}{}
+	return prefundedAccountsOutput(manifest)
 }
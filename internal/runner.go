@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Runner deploys the services described in a manifest on a specific target
+// (i.e. the local docker daemon or a Kubernetes cluster).
+type Runner interface {
+	// Run deploys all the services
+	Run() error
+
+	// Stop removes all the resources created by Run
+	Stop() error
+
+	// WaitForReady waits until all the services are running and healthy
+	WaitForReady(ctx context.Context, timeout time.Duration) error
+
+	// ExitErr signals when one of the services fails
+	ExitErr() <-chan error
+
+	// Instances returns the running instances that can be accessed from the host
+	// machine to run the ready hooks and the watchdogs.
+	Instances() []*instance
+}
+
+var (
+	_ Runner = &LocalRunner{}
+	_ Runner = &DockerRunner{}
+	_ Runner = &KubernetesRunner{}
+)
+
+// newInstances creates the concrete instances to run for each service in the manifest
+func newInstances(out *output, manifest *Manifest) ([]*instance, error) {
+	instances := []*instance{}
+	for _, service := range manifest.Services() {
+		log_output, err := out.LogOutput(service.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error getting log output: %w", err)
+		}
+		logs := &serviceLogs{
+			logRef: log_output,
+			path:   log_output.Name(),
+		}
+		component := service.component
+		if component == nil {
+			component = FindComponent(service.ComponentName)
+		}
+		if component == nil {
+			return nil, fmt.Errorf("component not found '%s'", service.ComponentName)
+		}
+		instance := &instance{
+			service:   service,
+			logs:      logs,
+			component: component,
+			manifest:  manifest,
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// resolveServiceTemplates resolves the templates (Service, Port, PortUDP) in the arguments and the
+// environment variables of the service with the functions provided by the runner.
+func resolveServiceTemplates(s *Service, funcs template.FuncMap) ([]string, map[string]string, error) {
+	var input map[string]interface{}
+
+	runTemplate := func(arg string) (string, error) {
+		tpl, err := template.New("").Funcs(funcs).Parse(arg)
+		if err != nil {
+			return "", err
+		}
+
+		var out strings.Builder
+		if err := tpl.Execute(&out, input); err != nil {
+			return "", err
+		}
+
+		return out.String(), nil
+	}
+
+	// apply the templates to the arguments
+	var argsResult []string
+	for _, arg := range s.Args {
+		newArg, err := runTemplate(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		argsResult = append(argsResult, newArg)
+	}
+
+	// apply the templates to the environment variables
+	envs := map[string]string{}
+	for k, v := range s.Env {
+		newV, err := runTemplate(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		envs[k] = newV
+	}
+
+	return argsResult, envs, nil
+}
@@ -0,0 +1,370 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Session is the record of a playground deployment started by the local runner.
+// Sessions are stored in the playground home directory so that later invocations
+// of the CLI can find the resources of a session (i.e. after 'cook --detach').
+type Session struct {
+	ID        string           `json:"id"`
+	OutputDir string           `json:"output_dir"`
+	Network   string           `json:"network"`
+	Runtime   ContainerRuntime `json:"runtime,omitempty"`
+	Services  []string         `json:"services"`
+	StartedAt time.Time        `json:"started_at"`
+
+	// HostPIDs are the processes of the services that run on the host (i.e. --use-native-reth)
+	HostPIDs []int `json:"host_pids,omitempty"`
+
+	// CreatedNetwork is set if the network was created by the playground and it is removed
+	// on stop. A network of the user (--network) is never removed.
+	CreatedNetwork bool `json:"created_network,omitempty"`
+}
+
+func sessionsDir() (string, error) {
+	homeDir, err := GetHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(homeDir, "sessions")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+	return dir, nil
+}
+
+func saveSession(session *Session) error {
+	dir, err := sessionsDir()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(session, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, session.ID+".json"), data, 0644)
+}
+
+func removeSession(id string) error {
+	dir, err := sessionsDir()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, id+".json")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// LoadSessions returns all the registered sessions sorted by start time (oldest first)
+func LoadSessions() ([]*Session, error) {
+	dir, err := sessionsDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions directory: %w", err)
+	}
+
+	sessions := []*Session{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var session Session
+		if err := json.Unmarshal(data, &session); err != nil {
+			return nil, fmt.Errorf("failed to decode session %s: %w", file.Name(), err)
+		}
+		sessions = append(sessions, &session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].StartedAt.Before(sessions[j].StartedAt)
+	})
+	return sessions, nil
+}
+
+// FindSession returns the session that matches the given id (or a prefix of it).
+// If the id is empty, it returns the most recent session.
+func FindSession(id string) (*Session, error) {
+	sessions, err := LoadSessions()
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("no playground sessions found")
+	}
+	if id == "" {
+		return sessions[len(sessions)-1], nil
+	}
+
+	var found *Session
+	for _, session := range sessions {
+		if !strings.HasPrefix(session.ID, id) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("session id '%s' is ambiguous", id)
+		}
+		found = session
+	}
+	if found == nil {
+		return nil, fmt.Errorf("session '%s' not found", id)
+	}
+	return found, nil
+}
+
+func sessionFilter(sessionID string, extra ...filters.KeyValuePair) filters.Args {
+	args := []filters.KeyValuePair{filters.Arg("label", fmt.Sprintf("playground.session=%s", sessionID))}
+	return filters.NewArgs(append(args, extra...)...)
+}
+
+// stopSessionContainers removes all the containers that belong to the given session
+func stopSessionContainers(client *client.Client, sessionID string) error {
+	containers, err := client.ContainerList(context.Background(), container.ListOptions{
+		Filters: sessionFilter(sessionID),
+		All:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting container list: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(containers))
+
+	errCh := make(chan error, len(containers))
+
+	for _, cont := range containers {
+		go func(contID string) {
+			defer wg.Done()
+			if err := client.ContainerRemove(context.Background(), contID, container.RemoveOptions{
+				RemoveVolumes: true,
+				RemoveLinks:   false,
+				Force:         true,
+			}); err != nil {
+				errCh <- fmt.Errorf("error removing container: %w", err)
+			}
+		}(cont.ID)
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopSessionHostProcesses kills the processes of the host services of a session
+// that are still running
+func stopSessionHostProcesses(pids []int) error {
+	for _, pid := range pids {
+		if !processAlive(pid) {
+			continue
+		}
+		proc, err := os.FindProcess(pid)
+		if err != nil {
+			return err
+		}
+		if err := proc.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return fmt.Errorf("failed to kill host process %d: %w", pid, err)
+		}
+	}
+	return nil
+}
+
+// processAlive returns whether the process with the pid is running
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
+
+// inspectSessionNetwork returns whether the network exists and whether it is created by
+// the playground, either for this session or for an earlier one (i.e. the default network)
+func inspectSessionNetwork(client *client.Client, name string) (exists bool, created bool, err error) {
+	resp, err := client.NetworkInspect(context.Background(), name, network.InspectOptions{})
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, true, nil
+		}
+		return false, false, fmt.Errorf("error inspecting network %s: %w", name, err)
+	}
+	return true, resp.Labels["playground"] == "true", nil
+}
+
+// removeSessionNetwork removes the network of a session once its containers are removed.
+// The network is kept if other containers (i.e. of another session) are still attached.
+func removeSessionNetwork(client *client.Client, name string) error {
+	resp, err := client.NetworkInspect(context.Background(), name, network.InspectOptions{})
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("error inspecting network %s: %w", name, err)
+	}
+	if len(resp.Containers) != 0 {
+		return nil
+	}
+	if err := client.NetworkRemove(context.Background(), name); err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("error removing network %s: %w", name, err)
+	}
+	return nil
+}
+
+// StopSession removes the containers, the host processes and the network of a session
+// and deletes it from the registry
+func StopSession(id string) (*Session, error) {
+	session, err := FindSession(id)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newContainerClient(session.Runtime)
+	if err != nil {
+		return nil, err
+	}
+	if err := stopSessionContainers(client, session.ID); err != nil {
+		return nil, err
+	}
+	if err := stopSessionHostProcesses(session.HostPIDs); err != nil {
+		return nil, err
+	}
+	if session.CreatedNetwork {
+		if err := removeSessionNetwork(client, session.Network); err != nil {
+			return nil, err
+		}
+	}
+	if err := removeSession(session.ID); err != nil {
+		return nil, fmt.Errorf("failed to remove session: %w", err)
+	}
+	return session, nil
+}
+
+// SessionStatus prints the registered sessions and the state of their services. The sessions
+// without containers nor host processes (i.e. the playground was killed) are pruned.
+func SessionStatus(out io.Writer) error {
+	sessions, err := LoadSessions()
+	if err != nil {
+		return err
+	}
+
+	var running int
+	for _, session := range sessions {
+		client, err := newContainerClient(session.Runtime)
+		if err != nil {
+			return err
+		}
+		containers, err := client.ContainerList(context.Background(), container.ListOptions{
+			Filters: sessionFilter(session.ID),
+			All:     true,
+		})
+		if err != nil {
+			return fmt.Errorf("error getting container list: %w", err)
+		}
+		if len(containers) == 0 && !slices.ContainsFunc(session.HostPIDs, processAlive) {
+			if err := removeSession(session.ID); err != nil {
+				return fmt.Errorf("failed to prune session %s: %w", session.ID, err)
+			}
+			continue
+		}
+		running++
+
+		states := map[string]string{}
+		for _, cont := range containers {
+			states[cont.Labels["service"]] = cont.State
+		}
+
+		fmt.Fprintf(out, "Session %s (started %s)\n", session.ID, session.StartedAt.Format(time.RFC3339))
+		fmt.Fprintf(out, "  output:  %s\n", session.OutputDir)
+		fmt.Fprintf(out, "  network: %s\n", session.Network)
+		for _, name := range session.Services {
+			state, ok := states[name]
+			if !ok {
+				state = "not found"
+			}
+			fmt.Fprintf(out, "  - %s: %s\n", name, state)
+		}
+	}
+	if running == 0 {
+		fmt.Fprintln(out, "No playground sessions running")
+	}
+	return nil
+}
+
+// SessionLogs writes the logs of a service of the given session to out. Services that
+// do not run inside a container (i.e. host services) are read from the session output folder.
+func SessionLogs(ctx context.Context, out io.Writer, sessionID, serviceName string, follow bool) error {
+	session, err := FindSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	client, err := newContainerClient(session.Runtime)
+	if err != nil {
+		return err
+	}
+	containers, err := client.ContainerList(ctx, container.ListOptions{
+		Filters: sessionFilter(session.ID, filters.Arg("label", "service="+serviceName)),
+		All:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting container list: %w", err)
+	}
+
+	if len(containers) == 0 {
+		logFile := filepath.Join(session.OutputDir, "logs", serviceName+".log")
+		content, err := os.ReadFile(logFile)
+		if err != nil {
+			return fmt.Errorf("service %s not found in session %s", serviceName, session.ID)
+		}
+		_, err = out.Write(content)
+		return err
+	}
+
+	logs, err := client.ContainerLogs(ctx, containers[0].ID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     follow,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting container logs: %w", err)
+	}
+	defer logs.Close()
+
+	if _, err := stdcopy.StdCopy(out, out, logs); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("error copying logs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	start := time.Now()
+	for i, id := range []string{"bbb-2", "aaa-1", "aaa-3"} {
+		session := &Session{
+			ID:        id,
+			OutputDir: "/tmp/" + id,
+			Network:   defaultNetworkName,
+			Services:  []string{"el", "beacon"},
+			HostPIDs:  []int{100 + i},
+			StartedAt: start.Add(time.Duration(i) * time.Minute),
+
+			CreatedNetwork: i == 0,
+		}
+		if err := saveSession(session); err != nil {
+			t.Fatalf("failed to save session: %v", err)
+		}
+	}
+
+	// the sessions are listed by start time
+	sessions, err := LoadSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 3 || sessions[0].ID != "bbb-2" || sessions[2].ID != "aaa-3" {
+		t.Fatalf("unexpected sessions %v", sessions)
+	}
+	if s := sessions[1]; s.OutputDir != "/tmp/aaa-1" || len(s.Services) != 2 || len(s.HostPIDs) != 1 || s.HostPIDs[0] != 101 {
+		t.Fatalf("unexpected session %+v", s)
+	}
+	// only the network created by the playground is removed on stop
+	if !sessions[0].CreatedNetwork || sessions[1].CreatedNetwork {
+		t.Fatalf("unexpected created networks %v and %v", sessions[0].CreatedNetwork, sessions[1].CreatedNetwork)
+	}
+
+	// an empty id is the most recent session and the ids can be shortened
+	for id, expected := range map[string]string{"": "aaa-3", "bbb": "bbb-2", "aaa-1": "aaa-1"} {
+		session, err := FindSession(id)
+		if err != nil {
+			t.Fatalf("failed to find session '%s': %v", id, err)
+		}
+		if session.ID != expected {
+			t.Fatalf("expected session %s for '%s', got %s", expected, id, session.ID)
+		}
+	}
+	for _, id := range []string{"aaa", "ccc"} {
+		if _, err := FindSession(id); err == nil {
+			t.Fatalf("expected an error for session '%s'", id)
+		}
+	}
+
+	if err := removeSession("aaa-3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeSession("aaa-3"); err != nil {
+		t.Fatalf("expected the removal of a missing session to succeed, got %v", err)
+	}
+	if _, err := FindSession("aaa"); err != nil {
+		t.Fatalf("expected a single session with prefix aaa, got %v", err)
+	}
+
+	// the files that are not sessions are skipped, the invalid sessions fail
+	dir, err := sessionsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, err := LoadSessions(); err != nil || len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d (%v)", len(sessions), err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSessions(); err == nil {
+		t.Fatal("expected an error for an invalid session")
+	}
+}
+
+func TestStopSessionHostProcesses(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+
+	pid := cmd.Process.Pid
+	if !processAlive(pid) {
+		t.Fatal("expected the host process to be alive")
+	}
+	if err := stopSessionHostProcesses([]int{pid}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the host process was not killed")
+	}
+	if processAlive(pid) {
+		t.Fatal("expected the host process to be dead")
+	}
+
+	// the processes that already exited are skipped
+	if err := stopSessionHostProcesses([]int{pid}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,8 +2,12 @@ package internal
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	txspammer "github.com/phylaxsystems/builder-playground/tx-spammer"
 )
 
 type nullableUint64Value struct {
@@ -68,3 +72,105 @@ func (n *MapStringFlag) Set(s string) error {
 	(*n)[k] = v
 	return nil
 }
+
+// ELClient is the L1 execution client used by the recipes
+type ELClient string
+
+const (
+	ELClientReth       ELClient = "reth"
+	ELClientGeth       ELClient = "geth"
+	ELClientNethermind ELClient = "nethermind"
+)
+
+func (e *ELClient) String() string {
+	return string(*e)
+}
+
+func (e *ELClient) Type() string {
+	return "string"
+}
+
+func (e *ELClient) Set(s string) error {
+	switch ELClient(s) {
+	case ELClientReth, ELClientGeth, ELClientNethermind:
+		*e = ELClient(s)
+	default:
+		return fmt.Errorf("invalid execution client '%s', expected reth, geth or nethermind", s)
+	}
+	return nil
+}
+
+// CLClient is the L1 consensus client (beacon node and validator client) used by the recipes
+type CLClient string
+
+const (
+	CLClientLighthouse CLClient = "lighthouse"
+	CLClientPrysm      CLClient = "prysm"
+	CLClientTeku       CLClient = "teku"
+)
+
+func (c *CLClient) String() string {
+	return string(*c)
+}
+
+func (c *CLClient) Type() string {
+	return "string"
+}
+
+func (c *CLClient) Set(s string) error {
+	switch CLClient(s) {
+	case CLClientLighthouse, CLClientPrysm, CLClientTeku:
+		*c = CLClient(s)
+	default:
+		return fmt.Errorf("invalid consensus client '%s', expected lighthouse, prysm or teku", s)
+	}
+	return nil
+}
+
+// txTypesValue is a comma separated list of the transaction types of the tx spammer
+type txTypesValue struct {
+	ptr *[]string
+}
+
+func (t txTypesValue) String() string {
+	return strings.Join(*t.ptr, ",")
+}
+
+func (t txTypesValue) Type() string {
+	return "strings"
+}
+
+func (t txTypesValue) Set(s string) error {
+	types := strings.Split(s, ",")
+	for _, txType := range types {
+		if !slices.Contains(txspammer.TxTypes, txType) {
+			return fmt.Errorf("invalid tx type '%s', expected one of %s", txType, strings.Join(txspammer.TxTypes, ", "))
+		}
+	}
+	*t.ptr = types
+	return nil
+}
+
+// addressValue is an address flag, it is the zero address if the flag is not set
+type addressValue struct {
+	ptr *gethcommon.Address
+}
+
+func (a addressValue) String() string {
+	if *a.ptr == (gethcommon.Address{}) {
+		return ""
+	}
+	return a.ptr.Hex()
+}
+
+func (a addressValue) Type() string {
+	return "address"
+}
+
+func (a addressValue) Set(s string) error {
+	if !gethcommon.IsHexAddress(s) {
+		return fmt.Errorf("invalid address '%s'", s)
+	}
+	*a.ptr = gethcommon.HexToAddress(s)
+	return nil
+}
@@ -2,39 +2,435 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v2"
 )
 
 type ServiceWatchdog interface {
-	Watchdog(out io.Writer, instance *instance, ctx context.Context) error
+	Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error
+}
+
+// watchdogThresholds are the thresholds of the watchdog checks and their default values.
+// The values are either a time.Duration or an uint64.
+var watchdogThresholds = map[string]map[string]interface{}{
+	// chain-head: time between two blocks on top of the block time
+	"chain-head": {"slack": 1 * time.Second},
+	// dispute-games: time between two output roots in the dispute game factory. The first
+	// one is only posted once the batcher has made some L2 blocks safe.
+	"dispute-games": {"timeout": 5 * time.Minute},
+	// flashblocks: time between two blocks on top of the block time, number of consecutive
+	// blocks built in a single flashblock and number of flashblocks missing in a block
+	// (the builder makes one every flashblocksInterval of the block time)
+	"flashblocks": {"slack": 1 * time.Second, "max-flat-blocks": uint64(5), "max-missing": uint64(2)},
+	// batches: time between two batches on top of the max channel duration of the batcher
+	"batches": {"slack": 1 * time.Minute},
+	// safe-head: time for the op-node to advance the safe head, the batches are posted
+	// every few L1 blocks (see OpBatcher.MaxChannelDuration)
+	"safe-head": {"window": 2 * time.Minute},
+	// finalized-head: time for the op-node to advance the finalized head, the first one
+	// is finalized once the L1 finalizes its first checkpoint (a few 6.4 minutes epochs)
+	"finalized-head": {"window": 30 * time.Minute},
+	// missed-slots: number of slots without a block in an epoch
+	"missed-slots": {"max-per-epoch": uint64(4)},
+	// finality: number of epochs between the current epoch and the finalized one. A healthy
+	// chain finalizes the epoch before the previous one.
+	"finality": {"max-epochs": uint64(4)},
+	// proposer-payloads: time between two payload attributes events
+	"proposer-payloads": {"timeout": 20 * time.Second},
 }
 
-func RunWatchdog(out *output, instances []*instance) error {
-	watchdogErr := make(chan error, len(instances))
+// WatchdogConfig overrides the thresholds of the watchdog checks. The keys of Checks are either
+// a check name (i.e. chain-head) or a check of a single service (i.e. op-geth/chain-head),
+// the latter takes precedence.
+type WatchdogConfig struct {
+	Checks map[string]map[string]interface{} `json:"checks"`
+}
 
-	output, err := out.LogOutput("watchdog")
+// LoadWatchdogConfig reads a watchdog config file. The format is derived from the
+// file extension (.json, .yaml or .yml).
+func LoadWatchdogConfig(path string) (*WatchdogConfig, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("failed to create log output: %w", err)
+		return nil, fmt.Errorf("failed to read watchdog config file: %w", err)
+	}
+
+	switch filepath.Ext(path) {
+	case ".json":
+	case ".yaml", ".yml":
+		var obj map[interface{}]interface{}
+		if err := yaml.Unmarshal(data, &obj); err != nil {
+			return nil, fmt.Errorf("failed to decode yaml watchdog config file: %w", err)
+		}
+		if data, err = json.Marshal(normalizeYAML(obj)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported watchdog config file extension '%s', expected .json, .yaml or .yml", filepath.Ext(path))
+	}
+
+	var config WatchdogConfig
+	if err := decodeStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode watchdog config file: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// validate checks that the thresholds of the config exist and converts their
+// values to the type of the defaults
+func (w *WatchdogConfig) validate() error {
+	for key, thresholds := range w.Checks {
+		check := key
+		if i := strings.LastIndex(key, "/"); i != -1 {
+			check = key[i+1:]
+		}
+		defaults, ok := watchdogThresholds[check]
+		if !ok {
+			return fmt.Errorf("unknown watchdog check '%s'", check)
+		}
+		for name, val := range thresholds {
+			def, ok := defaults[name]
+			if !ok {
+				return fmt.Errorf("unknown threshold '%s' of watchdog check '%s'", name, check)
+			}
+			var err error
+			switch def.(type) {
+			case time.Duration:
+				thresholds[name], err = parseThresholdDuration(val)
+			case uint64:
+				thresholds[name], err = parseThresholdUint(val)
+			}
+			if err != nil {
+				return fmt.Errorf("invalid threshold '%s' of watchdog check '%s': %w", name, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseThresholdDuration parses either a duration string (i.e. 1500ms) or a number of seconds
+func parseThresholdDuration(val interface{}) (time.Duration, error) {
+	switch v := val.(type) {
+	case string:
+		return time.ParseDuration(v)
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("expected a duration, got %v", val)
+}
+
+func parseThresholdUint(val interface{}) (uint64, error) {
+	if v, ok := val.(float64); ok && v >= 0 && v == float64(uint64(v)) {
+		return uint64(v), nil
+	}
+	return 0, fmt.Errorf("expected a positive integer, got %v", val)
+}
+
+// threshold returns the value of the threshold of the check for the service
+func (w *WatchdogConfig) threshold(service, check, name string) interface{} {
+	def, ok := watchdogThresholds[check][name]
+	if !ok {
+		panic(fmt.Sprintf("BUG: unknown threshold '%s' of watchdog check '%s'", name, check))
+	}
+	if w == nil {
+		return def
+	}
+	for _, key := range []string{service + "/" + check, check} {
+		if val, ok := w.Checks[key][name]; ok {
+			return val
+		}
+	}
+	return def
+}
+
+const (
+	WatchdogStatusOK     = "ok"
+	WatchdogStatusFailed = "failed"
+)
+
+// WatchdogEvent is the result of a single run of a watchdog check. The durations
+// of the observed value and the threshold are in seconds.
+type WatchdogEvent struct {
+	Time      time.Time   `json:"time"`
+	Service   string      `json:"service"`
+	Check     string      `json:"check"`
+	Status    string      `json:"status"`
+	Observed  interface{} `json:"observed,omitempty"`
+	Threshold interface{} `json:"threshold,omitempty"`
+	Message   string      `json:"message,omitempty"`
+}
+
+// WatchdogCheckSummary aggregates the events of a check of a service
+type WatchdogCheckSummary struct {
+	Service   string         `json:"service"`
+	Check     string         `json:"check"`
+	Events    uint64         `json:"events"`
+	Failures  uint64         `json:"failures"`
+	LastEvent *WatchdogEvent `json:"last_event"`
+}
+
+// WatchdogReport is the summary of a watchdog run written to watchdog-report.json
+type WatchdogReport struct {
+	Start    time.Time               `json:"start"`
+	End      time.Time               `json:"end"`
+	Status   string                  `json:"status"`
+	Error    string                  `json:"error,omitempty"`
+	Checks   []*WatchdogCheckSummary `json:"checks"`
+	Failures []*WatchdogEvent        `json:"failures"`
+
+	// Summaries are the summaries of the watchdogs by service (i.e. the relay audit)
+	Summaries map[string]interface{} `json:"summaries,omitempty"`
+}
+
+// Watchdog runs the watchdogs of the services. The events of the checks are appended
+// to watchdog-events.jsonl and summarized in watchdog-report.json in the output folder.
+type Watchdog struct {
+	out    *output
+	config *WatchdogConfig
+
+	lock      sync.Mutex
+	events    io.WriteCloser
+	start     time.Time
+	err       error
+	checks    map[string]*WatchdogCheckSummary
+	failures  []*WatchdogEvent
+	summaries map[string]interface{}
+}
+
+// NewWatchdog creates a watchdog, the config is optional
+func NewWatchdog(out *output, config *WatchdogConfig) *Watchdog {
+	return &Watchdog{
+		out:       out,
+		config:    config,
+		checks:    map[string]*WatchdogCheckSummary{},
+		summaries: map[string]interface{}{},
 	}
+}
 
+// Run runs the watchdogs of the instances until one of them fails. It returns
+// immediately if none of the instances has a watchdog.
+func (w *Watchdog) Run(instances []*instance) error {
+	var watchdogs []*instance
 	for _, s := range instances {
-		if watchdogFn, ok := s.component.(ServiceWatchdog); ok {
-			go func() {
-				if err := watchdogFn.Watchdog(output, s, context.Background()); err != nil {
-					watchdogErr <- fmt.Errorf("service %s watchdog failed: %w", s.service.Name, err)
-				}
-			}()
+		if _, ok := s.component.(ServiceWatchdog); ok {
+			watchdogs = append(watchdogs, s)
 		}
 	}
+	if len(watchdogs) == 0 {
+		return nil
+	}
+
+	logOutput, err := w.out.LogOutput("watchdog")
+	if err != nil {
+		return w.fail(fmt.Errorf("failed to create log output: %w", err))
+	}
+	events, err := os.Create(filepath.Join(w.out.dst, "watchdog-events.jsonl"))
+	if err != nil {
+		return w.fail(fmt.Errorf("failed to create events output: %w", err))
+	}
+
+	w.lock.Lock()
+	w.events = events
+	w.start = time.Now()
+	w.lock.Unlock()
+
+	watchdogErr := make(chan error, len(watchdogs))
+	for _, s := range watchdogs {
+		watchdogFn := s.component.(ServiceWatchdog)
+		out := &watchdogOutput{Writer: logOutput, service: s.service.Name, watchdog: w}
+		go func() {
+			if err := watchdogFn.Watchdog(out, s, context.Background()); err != nil {
+				if !out.failed.Load() {
+					// the watchdog did not report the failed check (i.e. the service is not reachable)
+					out.Fail("watchdog", nil, nil, "%s", err)
+				}
+				watchdogErr <- fmt.Errorf("service %s watchdog failed: %w", s.service.Name, err)
+			}
+		}()
+	}
 
 	// If any of the watchdogs fail, we return the error
 	if err := <-watchdogErr; err != nil {
-		return fmt.Errorf("failed to run watchdog: %w", err)
+		return w.fail(err)
 	}
 	return nil
 }
 
+// fail records the error of the run and writes the report right away, the process
+// might not live long enough to call Close
+func (w *Watchdog) fail(err error) error {
+	w.lock.Lock()
+	w.err = err
+	w.lock.Unlock()
+
+	if _, reportErr := w.writeReport(); reportErr != nil {
+		return fmt.Errorf("failed to run watchdog: %w (%v)", err, reportErr)
+	}
+	return fmt.Errorf("failed to run watchdog: %w", err)
+}
+
+// record appends the event to the events file and to the summary of its check
+func (w *Watchdog) record(event *WatchdogEvent) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.events != nil {
+		if data, err := json.Marshal(event); err == nil {
+			w.events.Write(append(data, '\n'))
+		}
+	}
+
+	key := event.Service + "/" + event.Check
+	summary, ok := w.checks[key]
+	if !ok {
+		summary = &WatchdogCheckSummary{Service: event.Service, Check: event.Check}
+		w.checks[key] = summary
+	}
+	summary.Events++
+	summary.LastEvent = event
+	if event.Status == WatchdogStatusFailed {
+		summary.Failures++
+		w.failures = append(w.failures, event)
+	}
+}
+
+// Report returns the summary of the events recorded so far
+func (w *Watchdog) Report() *WatchdogReport {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	report := &WatchdogReport{
+		Start:    w.start,
+		End:      time.Now(),
+		Status:   WatchdogStatusOK,
+		Checks:   []*WatchdogCheckSummary{},
+		Failures: append([]*WatchdogEvent{}, w.failures...),
+	}
+	if len(w.summaries) != 0 {
+		report.Summaries = map[string]interface{}{}
+		for service, summary := range w.summaries {
+			report.Summaries[service] = summary
+		}
+	}
+	if w.err != nil || len(w.failures) != 0 {
+		report.Status = WatchdogStatusFailed
+	}
+	if w.err != nil {
+		report.Error = w.err.Error()
+	}
+	for _, summary := range w.checks {
+		report.Checks = append(report.Checks, summary)
+	}
+	sort.Slice(report.Checks, func(i, j int) bool {
+		if report.Checks[i].Service != report.Checks[j].Service {
+			return report.Checks[i].Service < report.Checks[j].Service
+		}
+		return report.Checks[i].Check < report.Checks[j].Check
+	})
+	return report
+}
+
+// Close writes watchdog-report.json in the output folder and returns the report
+func (w *Watchdog) Close() (*WatchdogReport, error) {
+	w.lock.Lock()
+	if w.events != nil {
+		w.events.Close()
+		w.events = nil
+	}
+	w.lock.Unlock()
+
+	return w.writeReport()
+}
+
+// writeReport writes the report of the events recorded so far in watchdog-report.json
+func (w *Watchdog) writeReport() (*WatchdogReport, error) {
+	report := w.Report()
+	if err := w.out.WriteFile("watchdog-report.json", report); err != nil {
+		return nil, fmt.Errorf("failed to write watchdog report: %w", err)
+	}
+	return report, nil
+}
+
+// watchdogOutput is the output of the watchdog of a service. It writes the logs and
+// records the events of the checks of the service.
+type watchdogOutput struct {
+	io.Writer
+
+	service  string
+	watchdog *Watchdog
+
+	// failed is set once a check fails, the watchers of a service can run concurrently
+	failed atomic.Bool
+}
+
+// Duration returns the duration threshold of the check
+func (o *watchdogOutput) Duration(check, name string) time.Duration {
+	return o.watchdog.config.threshold(o.service, check, name).(time.Duration)
+}
+
+// Uint returns the integer threshold of the check
+func (o *watchdogOutput) Uint(check, name string) uint64 {
+	return o.watchdog.config.threshold(o.service, check, name).(uint64)
+}
+
+// Summary sets the summary of the watchdog of the service that is added to the report.
+// The summary must not be modified afterwards.
+func (o *watchdogOutput) Summary(summary interface{}) {
+	o.watchdog.lock.Lock()
+	defer o.watchdog.lock.Unlock()
+	o.watchdog.summaries[o.service] = summary
+}
+
+// WriteFile writes a file of the watchdog in the output folder
+func (o *watchdogOutput) WriteFile(dst string, data interface{}) error {
+	return o.watchdog.out.WriteFile(dst, data)
+}
+
+// Pass records a successful run of the check
+func (o *watchdogOutput) Pass(check string, observed, threshold interface{}, format string, args ...interface{}) {
+	o.event(check, WatchdogStatusOK, observed, threshold, fmt.Sprintf(format, args...))
+}
+
+// Fail records a failed run of the check and returns the error of the failure
+func (o *watchdogOutput) Fail(check string, observed, threshold interface{}, format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	o.failed.Store(true)
+	o.event(check, WatchdogStatusFailed, observed, threshold, err.Error())
+	return err
+}
+
+func (o *watchdogOutput) event(check, status string, observed, threshold interface{}, msg string) {
+	o.watchdog.record(&WatchdogEvent{
+		Time:      time.Now(),
+		Service:   o.service,
+		Check:     check,
+		Status:    status,
+		Observed:  watchdogValue(observed),
+		Threshold: watchdogValue(threshold),
+		Message:   msg,
+	})
+}
+
+// watchdogValue encodes the durations in seconds
+func watchdogValue(val interface{}) interface{} {
+	if d, ok := val.(time.Duration); ok {
+		return d.Seconds()
+	}
+	return val
+}
+
 func CompleteReady(instances []*instance) error {
 	for _, s := range instances {
 		if readyFn, ok := s.component.(ServiceReady); ok {
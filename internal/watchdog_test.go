@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadWatchdogConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "watchdog.yaml")
+	content := `
+checks:
+  chain-head:
+    slack: 3s
+  op-geth/chain-head:
+    slack: 0.5
+  flashblocks:
+    max-flat-blocks: 10
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadWatchdogConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	out := func(service string) *watchdogOutput {
+		return &watchdogOutput{service: service, watchdog: NewWatchdog(nil, config)}
+	}
+	if slack := out("el").Duration("chain-head", "slack"); slack != 3*time.Second {
+		t.Fatalf("expected the check threshold, got %s", slack)
+	}
+	if slack := out("op-geth").Duration("chain-head", "slack"); slack != 500*time.Millisecond {
+		t.Fatalf("expected the service threshold, got %s", slack)
+	}
+	if slack := out("flashblocks-proxy").Duration("flashblocks", "slack"); slack != time.Second {
+		t.Fatalf("expected the default threshold, got %s", slack)
+	}
+	if flat := out("flashblocks-proxy").Uint("flashblocks", "max-flat-blocks"); flat != 10 {
+		t.Fatalf("expected 10 flat blocks, got %d", flat)
+	}
+
+	for _, invalid := range []string{
+		`{"checks": {"unknown": {"slack": "1s"}}}`,
+		`{"checks": {"chain-head": {"timeout": "1s"}}}`,
+		`{"checks": {"chain-head": {"slack": "abc"}}}`,
+		`{"checks": {"flashblocks": {"max-flat-blocks": 1.5}}}`,
+		`{"check": {}}`,
+	} {
+		path := filepath.Join(dir, "watchdog.json")
+		if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadWatchdogConfig(path); err == nil {
+			t.Fatalf("expected an error for %s", invalid)
+		}
+	}
+}
+
+func TestWatchdogReport(t *testing.T) {
+	out := &output{dst: t.TempDir()}
+	wd := NewWatchdog(out, nil)
+
+	svc := &watchdogOutput{service: "el", watchdog: wd}
+	svc.Pass("chain-head", 2*time.Second, 13*time.Second, "chain head %d", 1)
+	err := svc.Fail("chain-head", 13*time.Second, 13*time.Second, "chain head not advancing")
+	if err == nil || err.Error() != "chain head not advancing" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := wd.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(out.dst, "watchdog-report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report WatchdogReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != WatchdogStatusFailed {
+		t.Fatalf("expected a failed report, got %s", report.Status)
+	}
+	if len(report.Checks) != 1 || report.Checks[0].Events != 2 || report.Checks[0].Failures != 1 {
+		t.Fatalf("unexpected checks %+v", report.Checks)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].Observed != 13.0 || !strings.Contains(report.Failures[0].Message, "not advancing") {
+		t.Fatalf("unexpected failures %+v", report.Failures)
+	}
+}
+
+type failingWatchdog struct{}
+
+func (f *failingWatchdog) Run(service *Service, ctx *ExContext) {}
+
+func (f *failingWatchdog) Name() string {
+	return "failing"
+}
+
+func (f *failingWatchdog) Watchdog(out *watchdogOutput, instance *instance, ctx context.Context) error {
+	return out.Fail("chain-head", nil, nil, "chain head not advancing")
+}
+
+func TestWatchdogRun(t *testing.T) {
+	// without watchdogs Run returns right away
+	out := &output{dst: t.TempDir()}
+	wd := NewWatchdog(out, nil)
+	if err := wd.Run([]*instance{{service: &Service{Name: "mev-boost"}, component: &MevBoost{}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the report is written as soon as a watchdog fails, before Close
+	wd = NewWatchdog(out, nil)
+	if err := wd.Run([]*instance{{service: &Service{Name: "el"}, component: &failingWatchdog{}}}); err == nil {
+		t.Fatal("expected the watchdog to fail")
+	}
+	data, err := os.ReadFile(filepath.Join(out.dst, "watchdog-report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report WatchdogReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != WatchdogStatusFailed || !strings.Contains(report.Error, "not advancing") || len(report.Failures) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if _, err := wd.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,17 +1,25 @@
 package internal
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
+	"sort"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/flashbots/mev-boost-relay/beaconclient"
 	mevRCommon "github.com/flashbots/mev-boost-relay/common"
+	"github.com/gorilla/websocket"
 )
 
 func waitForChainAlive(ctx context.Context, logOutput io.Writer, beaconNodeURL string, timeout time.Duration) error {
@@ -65,10 +73,10 @@ func waitForChainAlive(ctx context.Context, logOutput io.Writer, beaconNodeURL s
 
 // validateProposerPayloads validates that payload attribute events are being broadcasted by the beacon node
 // in the correct order without any missing slots.
-func validateProposerPayloads(logOutput io.Writer, beaconNodeURL string) error {
+func validateProposerPayloads(out *watchdogOutput, beaconNodeURL string) error {
 	// Test that blocks are being produced
 	log := mevRCommon.LogSetup(false, "info").WithField("context", "validateProposerPayloads")
-	log.Logger.Out = logOutput
+	log.Logger.Out = out
 
 	clt := beaconclient.NewProdBeaconInstance(log, beaconNodeURL, beaconNodeURL)
 
@@ -78,6 +86,8 @@ func validateProposerPayloads(logOutput io.Writer, beaconNodeURL string) error {
 
 	log.Infof("Chain is alive. Subscribing to head events")
 
+	timeout := out.Duration("proposer-payloads", "timeout")
+
 	var lastSlot uint64
 	for {
 		select {
@@ -87,89 +97,313 @@ func validateProposerPayloads(logOutput io.Writer, beaconNodeURL string) error {
 			// If we are being notified of a new slot, validate that the slots are contiguous
 			// Note that lighthouse might send multiple updates for the same slot.
 			if lastSlot != 0 && lastSlot != head.Data.ProposalSlot && lastSlot+1 != head.Data.ProposalSlot {
-				return fmt.Errorf("slot mismatch, expected %d, got %d", lastSlot+1, head.Data.ProposalSlot)
+				return out.Fail("proposer-payloads", head.Data.ProposalSlot, lastSlot+1, "slot mismatch, expected %d, got %d", lastSlot+1, head.Data.ProposalSlot)
 			}
 			// if the network did not miss any initial slots, lighthouse will send payload attribute updates
 			// of the form: (slot = slot, parent block number = slot - 2), (slot, slot - 1).
 			// The -2 is in case we want to handle reorgs in the chain.
 			// We need to validate that at least the difference between the parent block number and the slot is 2.
 			if head.Data.ProposalSlot-head.Data.ParentBlockNumber > 2 {
-				return fmt.Errorf("parent block too big %d", head.Data.ParentBlockNumber)
+				return out.Fail("proposer-payloads", head.Data.ProposalSlot-head.Data.ParentBlockNumber, 2, "parent block too big %d", head.Data.ParentBlockNumber)
+			}
+			if lastSlot != head.Data.ProposalSlot {
+				out.Pass("proposer-payloads", head.Data.ProposalSlot, nil, "payload attributes for slot %d", head.Data.ProposalSlot)
 			}
 
 			lastSlot = head.Data.ProposalSlot
-		case <-time.After(20 * time.Second):
-			return fmt.Errorf("timeout waiting for block")
+		case <-time.After(timeout):
+			return out.Fail("proposer-payloads", timeout, timeout, "timeout waiting for block")
 		}
 	}
 }
 
-func watchProposerPayloads(beaconNodeURL string) error {
-	getProposerPayloadDelivered := func() ([]*mevRCommon.BidTraceV2JSON, error) {
-		resp, err := http.Get(fmt.Sprintf("%s/relay/v1/data/bidtraces/proposer_payload_delivered", beaconNodeURL))
+// relaySlotAudit is the audit of the bids and the delivered payload of a slot
+type relaySlotAudit struct {
+	Slot           uint64 `json:"slot"`
+	BlocksReceived uint64 `json:"blocks_received"`
+	// Truncated is set if the relay returned relayBidsLimit blocks, the counts of the slot are a lower bound
+	Truncated   bool   `json:"truncated,omitempty"`
+	Builders    uint64 `json:"builders"`
+	TopBid      string `json:"top_bid"`
+	Winner      string `json:"winner,omitempty"`
+	BlockNumber uint64 `json:"block_number,omitempty"`
+	BlockHash   string `json:"block_hash,omitempty"`
+	Value       string `json:"value,omitempty"`
+}
+
+// relayBuilderAudit are the stats of the submissions of a builder. The bid values are the
+// best bid of the builder in every slot it submitted a block.
+type relayBuilderAudit struct {
+	BlocksReceived uint64             `json:"blocks_received"`
+	SlotsBid       uint64             `json:"slots_bid"`
+	Wins           uint64             `json:"wins"`
+	BidValue       *valueDistribution `json:"bid_value"`
+	WinValue       *valueDistribution `json:"win_value"`
+
+	bids []*big.Int
+	wins []*big.Int
+}
+
+// valueDistribution summarizes a list of values in wei
+type valueDistribution struct {
+	Count  int    `json:"count"`
+	Min    string `json:"min"`
+	Median string `json:"median"`
+	Max    string `json:"max"`
+	Total  string `json:"total"`
+}
+
+func newValueDistribution(values []*big.Int) *valueDistribution {
+	if len(values) == 0 {
+		return &valueDistribution{}
+	}
+	sorted := append([]*big.Int{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+	total := new(big.Int)
+	for _, val := range sorted {
+		total.Add(total, val)
+	}
+	return &valueDistribution{
+		Count:  len(sorted),
+		Min:    sorted[0].String(),
+		Median: sorted[len(sorted)/2].String(),
+		Max:    sorted[len(sorted)-1].String(),
+		Total:  total.String(),
+	}
+}
+
+// relayBidsLimit is the maximum number of blocks returned by builder_blocks_received. The relay
+// does not support a cursor for the blocks received, so the slots with more blocks are truncated.
+const relayBidsLimit = 500
+
+// relayAuditSummary is the summary of the relay audit reported at shutdown
+type relayAuditSummary struct {
+	Slots             uint64 `json:"slots"`
+	BlocksReceived    uint64 `json:"blocks_received"`
+	PayloadsDelivered uint64 `json:"payloads_delivered"`
+	PayloadsCanonical uint64 `json:"payloads_canonical"`
+	Builders          int    `json:"builders"`
+}
+
+func (r *relayAuditSummary) String() string {
+	return fmt.Sprintf("%d slots, %d blocks received from %d builders, %d payloads delivered (%d canonical)",
+		r.Slots, r.BlocksReceived, r.Builders, r.PayloadsDelivered, r.PayloadsCanonical)
+}
+
+// watchRelayAudit audits the bids received and the payloads delivered by the relay with its data API.
+// Every slot up to the last delivered payload is audited: the blocks received from the builders and the
+// winning bid. The delivered blocks must be part of the canonical chain of the execution layer, the
+// check is skipped if elURL is not set.
+// The stats of the builders and the slots are written to relay-stats.json in the output folder.
+func watchRelayAudit(out *watchdogOutput, relayURL string, elURL string) error {
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchRelayAudit").WithField("relay", relayURL)
+	log.Logger.Out = out
+
+	dataAPI := func(path string, obj interface{}) error {
+		resp, err := http.Get(relayURL + path)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		defer resp.Body.Close()
 
-		data, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status code %d for %s", resp.StatusCode, path)
 		}
-
-		var payloadDeliveredList []*mevRCommon.BidTraceV2JSON
-		if err := json.Unmarshal(data, &payloadDeliveredList); err != nil {
+		return json.NewDecoder(resp.Body).Decode(obj)
+	}
+	getPayloadsDelivered := func() ([]*mevRCommon.BidTraceV2JSON, error) {
+		var payloads []*mevRCommon.BidTraceV2JSON
+		if err := dataAPI("/relay/v1/data/bidtraces/proposer_payload_delivered", &payloads); err != nil {
 			return nil, err
 		}
-		return payloadDeliveredList, nil
+		return payloads, nil
 	}
 
-	// Wait for at least 10 seconds for Mev-boost to start
-	timerC := time.After(10 * time.Second)
-LOOP:
-	for {
-		select {
-		case <-timerC:
+	// wait for the relay to start
+	var err error
+	for i := 0; i < 30; i++ {
+		if _, err = getPayloadsDelivered(); err == nil {
 			break
-		case <-time.After(2 * time.Second):
-			if _, err := getProposerPayloadDelivered(); err == nil {
-				break LOOP
-			}
 		}
+		time.Sleep(1 * time.Second)
+	}
+	if err != nil {
+		log.Warnf("Failed to query the relay data api: %v", err)
 	}
 
-	// This is not the most efficient solution since we are querying the endpoint for the full list of payloads
-	// every 2 seconds. It should be fine for the kind of workloads expected to run.
+	var rpcClient *rpc.Client
+	if elURL != "" {
+		if rpcClient, err = rpc.Dial(elURL); err != nil {
+			return err
+		}
+		defer rpcClient.Close()
+	}
+
+	var slots []*relaySlotAudit
+	builders := map[string]*relayBuilderAudit{}
+	summary := &relayAuditSummary{}
+
+	writeStats := func() error {
+		for _, builder := range builders {
+			builder.BidValue = newValueDistribution(builder.bids)
+			builder.WinValue = newValueDistribution(builder.wins)
+		}
+		stats := map[string]interface{}{
+			"builders": builders,
+			"slots":    slots,
+		}
+		if err := out.WriteFile("relay-stats.json", stats); err != nil {
+			return fmt.Errorf("failed to write the relay stats: %w", err)
+		}
+		// copy the summary since it is read when the watchdog stops
+		summary.Builders = len(builders)
+		snapshot := *summary
+		out.Summary(&snapshot)
+		return nil
+	}
 
-	lastSlot := uint64(0)
+	// pending are the delivered payloads that are not in the execution layer yet
+	var pending []*mevRCommon.BidTraceV2JSON
+	var lastSlot uint64
 
+	// the errors of the relay and the execution layer are transient, the audit is resumed in the next poll
 	for {
 		time.Sleep(2 * time.Second)
 
-		vals, err := getProposerPayloadDelivered()
+		payloads, err := getPayloadsDelivered()
 		if err != nil {
-			fmt.Println("Error getting proposer payloads:", err)
+			log.Warnf("Failed to get the delivered payloads: %v", err)
 			continue
 		}
+		delivered := map[uint64]*mevRCommon.BidTraceV2JSON{}
+		var headSlot uint64
+		for _, payload := range payloads {
+			delivered[payload.Slot] = payload
+			if payload.Slot > headSlot {
+				headSlot = payload.Slot
+			}
+		}
 
-		for _, val := range vals {
-			if val.Slot <= lastSlot {
+		if lastSlot == 0 && headSlot > 0 {
+			// the audit starts with the first delivered payload
+			lastSlot = headSlot - 1
+		}
+		changed := lastSlot < headSlot
+		for slot := lastSlot + 1; slot <= headSlot; slot++ {
+			var bids []*mevRCommon.BidTraceV2WithTimestampJSON
+			if err := dataAPI(fmt.Sprintf("/relay/v1/data/bidtraces/builder_blocks_received?slot=%d&limit=%d", slot, relayBidsLimit), &bids); err != nil {
+				log.Warnf("Failed to get the blocks received in slot %d: %v", slot, err)
+				break
+			}
+
+			audit := &relaySlotAudit{Slot: slot, BlocksReceived: uint64(len(bids)), TopBid: "0"}
+			if len(bids) >= relayBidsLimit {
+				log.Warnf("Slot %d: the relay returned the maximum of %d blocks received, the counts of the slot are truncated", slot, relayBidsLimit)
+				audit.Truncated = true
+			}
+			topBid := new(big.Int)
+			bestBids := map[string]*big.Int{}
+			for _, bid := range bids {
+				value, ok := new(big.Int).SetString(bid.Value, 10)
+				if !ok {
+					return fmt.Errorf("invalid value '%s' of a bid in slot %d", bid.Value, slot)
+				}
+				builder, ok := builders[bid.BuilderPubkey]
+				if !ok {
+					builder = &relayBuilderAudit{}
+					builders[bid.BuilderPubkey] = builder
+				}
+				builder.BlocksReceived++
+				if best, ok := bestBids[bid.BuilderPubkey]; !ok || value.Cmp(best) > 0 {
+					bestBids[bid.BuilderPubkey] = value
+				}
+				if value.Cmp(topBid) > 0 {
+					topBid = value
+				}
+			}
+			for pubkey, best := range bestBids {
+				builders[pubkey].SlotsBid++
+				builders[pubkey].bids = append(builders[pubkey].bids, best)
+			}
+			audit.Builders = uint64(len(bestBids))
+			audit.TopBid = topBid.String()
+			summary.Slots++
+			summary.BlocksReceived += audit.BlocksReceived
+
+			if payload, ok := delivered[slot]; ok {
+				value, ok := new(big.Int).SetString(payload.Value, 10)
+				if !ok {
+					return fmt.Errorf("invalid value '%s' of the payload of slot %d", payload.Value, slot)
+				}
+				builder, ok := builders[payload.BuilderPubkey]
+				if !ok {
+					builder = &relayBuilderAudit{}
+					builders[payload.BuilderPubkey] = builder
+				}
+				builder.Wins++
+				builder.wins = append(builder.wins, value)
+
+				audit.Winner, audit.BlockNumber, audit.BlockHash, audit.Value = payload.BuilderPubkey, payload.BlockNumber, payload.BlockHash, payload.Value
+				summary.PayloadsDelivered++
+				if rpcClient != nil {
+					pending = append(pending, payload)
+				}
+
+				log.Infof("Slot %d: %d blocks received from %d builders, block %d delivered from %s with value %s", slot, audit.BlocksReceived, audit.Builders, payload.BlockNumber, payload.BuilderPubkey, payload.Value)
+				out.Pass("winning-bids", value, nil, "slot %d won by %s with %d blocks received from %d builders, top bid %s", slot, payload.BuilderPubkey, audit.BlocksReceived, audit.Builders, topBid)
+			} else {
+				log.Infof("Slot %d: %d blocks received from %d builders, no payload delivered", slot, audit.BlocksReceived, audit.Builders)
+			}
+			slots = append(slots, audit)
+			lastSlot = slot
+		}
+
+		// cross-check the delivered blocks once the execution layer has reached them
+		var head hexutil.Uint64
+		if len(pending) != 0 {
+			if err := rpcClient.CallContext(context.Background(), &head, "eth_blockNumber"); err != nil {
+				log.Warnf("Failed to get the block number: %v", err)
+			}
+		}
+		remaining := pending[:0]
+		for _, payload := range pending {
+			if payload.BlockNumber > uint64(head) {
+				remaining = append(remaining, payload)
+				continue
+			}
+			// the hash is not computed from the header since it depends on the fork of the client
+			var block struct {
+				Hash common.Hash `json:"hash"`
+			}
+			if err := rpcClient.CallContext(context.Background(), &block, "eth_getBlockByNumber", hexutil.EncodeUint64(payload.BlockNumber), false); err != nil {
+				log.Warnf("Failed to get block %d: %v", payload.BlockNumber, err)
+				remaining = append(remaining, payload)
 				continue
 			}
+			if block.Hash != common.HexToHash(payload.BlockHash) {
+				return out.Fail("canonical-payloads", block.Hash.Hex(), payload.BlockHash, "payload of slot %d (block %d, %s) delivered by the relay is not canonical, el has %s", payload.Slot, payload.BlockNumber, payload.BlockHash, block.Hash.Hex())
+			}
+			summary.PayloadsCanonical++
+			changed = true
+			out.Pass("canonical-payloads", block.Hash.Hex(), payload.BlockHash, "payload of slot %d is canonical at block %d", payload.Slot, payload.BlockNumber)
+		}
+		pending = remaining
 
-			fmt.Printf("Block Proposed: Slot: %d, Builder: %s, Block: %d\n", val.Slot, val.BuilderPubkey, val.BlockNumber)
-			lastSlot = val.Slot
+		if changed {
+			if err := writeStats(); err != nil {
+				log.Warnf("%v", err)
+			}
 		}
 	}
 }
 
 // watchChainHead watches the chain head and ensures that it is advancing
-func watchChainHead(logOutput io.Writer, elURL string, blockTime time.Duration) error {
+func watchChainHead(out *watchdogOutput, elURL string, blockTime time.Duration) error {
 	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchChainHead").WithField("el", elURL)
-	log.Logger.Out = logOutput
+	log.Logger.Out = out
 
 	// add some wiggle room to block time
-	blockTime = blockTime + 1*time.Second
+	maxBlockTime := blockTime + out.Duration("chain-head", "slack")
 
 	rpcClient, err := rpc.Dial(elURL)
 	if err != nil {
@@ -177,9 +411,10 @@ func watchChainHead(logOutput io.Writer, elURL string, blockTime time.Duration)
 	}
 
 	var latestBlock *uint64
+	var latestTime time.Time
 	clt := ethclient.NewClient(rpcClient)
 
-	timeout := time.NewTimer(blockTime)
+	timeout := time.NewTimer(maxBlockTime)
 	defer timeout.Stop()
 
 	for {
@@ -193,16 +428,524 @@ func watchChainHead(logOutput io.Writer, elURL string, blockTime time.Duration)
 				continue
 			}
 			log.Infof("Chain head: %d", num)
-			latestBlock = &num
+			if latestBlock != nil {
+				out.Pass("chain-head", time.Since(latestTime), maxBlockTime, "chain head %d", num)
+			}
+			latestBlock, latestTime = &num, time.Now()
 
 			// Reset timeout since we saw a new block
 			if !timeout.Stop() {
 				<-timeout.C
 			}
-			timeout.Reset(blockTime)
+			timeout.Reset(maxBlockTime)
 
 		case <-timeout.C:
-			return fmt.Errorf("chain head for %s not advancing", elURL)
+			return out.Fail("chain-head", maxBlockTime, maxBlockTime, "chain head for %s not advancing", elURL)
+		}
+	}
+}
+
+var (
+	// selectors of the DisputeGameFactory and the dispute game contracts
+	gameCountSelector     = hexutil.MustDecode("0x4d1975b4") // gameCount()
+	gameAtIndexSelector   = hexutil.MustDecode("0xbb8aa1fc") // gameAtIndex(uint256)
+	gameStatusSelector    = hexutil.MustDecode("0x200d2ed2") // status()
+	gameRootClaimSelector = hexutil.MustDecode("0xbcef3b55") // rootClaim()
+	gameL2BlockSelector   = hexutil.MustDecode("0x8b85902b") // l2BlockNumber()
+)
+
+// GameStatus values of the dispute games
+const (
+	gameStatusInProgress     = 0
+	gameStatusChallengerWins = 1
+	gameStatusDefenderWins   = 2
+)
+
+// watchDisputeGames watches the games created in the dispute game factory and ensures that new output
+// roots are posted within the timeout and that none of the games is resolved in favour of the challenger
+func watchDisputeGames(out *watchdogOutput, l1URL string, factoryAddr string) error {
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchDisputeGames").WithField("factory", factoryAddr)
+	log.Logger.Out = out
+
+	timeout := out.Duration("dispute-games", "timeout")
+
+	factory := common.HexToAddress(factoryAddr)
+
+	rpcClient, err := rpc.Dial(l1URL)
+	if err != nil {
+		return err
+	}
+	clt := ethclient.NewClient(rpcClient)
+
+	call := func(to common.Address, data []byte) ([]byte, error) {
+		return clt.CallContract(context.Background(), ethereum.CallMsg{To: &to, Data: data}, nil)
+	}
+	callUint := func(to common.Address, data []byte) (uint64, error) {
+		res, err := call(to, data)
+		if err != nil {
+			return 0, err
+		}
+		return new(big.Int).SetBytes(res).Uint64(), nil
+	}
+
+	// inProgress are the games that are not resolved yet by their index
+	inProgress := map[common.Address]uint64{}
+	var gameCount uint64
+	lastGame := time.Now()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-time.After(2 * time.Second):
+			count, err := callUint(factory, gameCountSelector)
+			if err != nil {
+				return fmt.Errorf("failed to get the game count: %w", err)
+			}
+			for i := gameCount; i < count; i++ {
+				res, err := call(factory, append(gameAtIndexSelector, common.BigToHash(new(big.Int).SetUint64(i)).Bytes()...))
+				if err != nil || len(res) != 96 {
+					return fmt.Errorf("failed to get the game %d: %w", i, err)
+				}
+				game := common.BytesToAddress(res[64:96])
+
+				l2Block, err := callUint(game, gameL2BlockSelector)
+				if err != nil {
+					return fmt.Errorf("failed to get the l2 block of game %d: %w", i, err)
+				}
+				rootClaim, err := call(game, gameRootClaimSelector)
+				if err != nil {
+					return fmt.Errorf("failed to get the root claim of game %d: %w", i, err)
+				}
+				log.Infof("Output root posted: game %d (%s), l2 block %d, root %s", i, game.Hex(), l2Block, common.BytesToHash(rootClaim).Hex())
+				inProgress[game] = i
+			}
+			if count > gameCount {
+				out.Pass("dispute-games", time.Since(lastGame), timeout, "%d output roots posted", count-gameCount)
+				gameCount, lastGame = count, time.Now()
+
+				// Reset timeout since we saw a new output root
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(timeout)
+			}
+
+			for game, index := range inProgress {
+				status, err := callUint(game, gameStatusSelector)
+				if err != nil {
+					return fmt.Errorf("failed to get the status of game %d: %w", index, err)
+				}
+				switch status {
+				case gameStatusChallengerWins:
+					return out.Fail("dispute-games", "challenger-wins", "defender-wins", "game %d (%s) resolved in favour of the challenger, an invalid output root was posted", index, game.Hex())
+				case gameStatusDefenderWins:
+					log.Infof("Game %d resolved in favour of the defender", index)
+					delete(inProgress, game)
+				}
+			}
+
+		case <-timer.C:
+			return out.Fail("dispute-games", timeout, timeout, "no output root posted in the dispute game factory in the last %s", timeout)
+		}
+	}
+}
+
+// flashblock is the part of a flashblocks message used by the watcher
+type flashblock struct {
+	Index    uint64 `json:"index"`
+	Metadata struct {
+		BlockNumber uint64 `json:"block_number"`
+	} `json:"metadata"`
+}
+
+// watchFlashblocks subscribes to the flashblocks stream and checks that every block is built
+// in several flashblocks, one every interval, and that the blocks start at the cadence of the
+// block time.
+func watchFlashblocks(out *watchdogOutput, wsURL string, blockTime, interval time.Duration) error {
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchFlashblocks").WithField("ws", wsURL)
+	log.Logger.Out = out
+
+	// the proxy might not be connected to rollup-boost yet
+	var conn *websocket.Conn
+	var err error
+	for i := 0; i < 30; i++ {
+		if conn, _, err = websocket.DefaultDialer.Dial(wsURL, nil); err == nil {
+			break
+		}
+		time.Sleep(1 * time.Second)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to the flashblocks stream: %w", err)
+	}
+	defer conn.Close()
+
+	msgCh := make(chan *flashblock)
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			var msg flashblock
+			if err := json.Unmarshal(data, &msg); err != nil {
+				errCh <- fmt.Errorf("failed to decode flashblock: %w", err)
+				return
+			}
+			select {
+			case msgCh <- &msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// add some wiggle room to block time
+	maxBlockTime := blockTime + out.Duration("flashblocks", "slack")
+	maxFlatBlocks := out.Uint("flashblocks", "max-flat-blocks")
+	maxMissing := out.Uint("flashblocks", "max-missing")
+	expected := uint64(blockTime / interval)
+
+	timeout := time.NewTimer(maxBlockTime)
+	defer timeout.Stop()
+
+	// the first block is skipped since the stream can start in the middle of it
+	var blockNumber, count, flatBlocks uint64
+	var blockStart time.Time
+
+	for {
+		select {
+		case msg := <-msgCh:
+			now := time.Now()
+			if msg.Index == 0 {
+				if !blockStart.IsZero() {
+					elapsed := now.Sub(blockStart)
+					log.Infof("Block %d: %d flashblocks in %s", blockNumber, count, elapsed.Round(time.Millisecond))
+
+					if elapsed > maxBlockTime {
+						return out.Fail("flashblocks", elapsed, maxBlockTime, "block %d took %s, expected a block every %s", blockNumber, elapsed, blockTime)
+					}
+					if count <= 1 {
+						flatBlocks++
+						if flatBlocks >= maxFlatBlocks {
+							return out.Fail("flashblocks", flatBlocks, maxFlatBlocks, "the last %d blocks were built without sub-blocks", flatBlocks)
+						}
+					} else {
+						flatBlocks = 0
+					}
+					if count+maxMissing < expected {
+						return out.Fail("flashblocks", expected-count, maxMissing, "block %d built in %d flashblocks, expected %d (one every %s)", blockNumber, count, expected, interval)
+					}
+					out.Pass("flashblocks", count, nil, "block %d built in %d flashblocks in %s", blockNumber, count, elapsed.Round(time.Millisecond))
+				}
+				blockNumber, count, blockStart = msg.Metadata.BlockNumber, 0, now
+			}
+			count++
+
+			// Reset timeout since we saw a new flashblock
+			if !timeout.Stop() {
+				<-timeout.C
+			}
+			timeout.Reset(maxBlockTime)
+
+		case err := <-errCh:
+			return fmt.Errorf("flashblocks stream failed: %w", err)
+
+		case <-timeout.C:
+			return out.Fail("flashblocks", maxBlockTime, maxBlockTime, "no flashblock received in the last %s", maxBlockTime)
+		}
+	}
+}
+
+// opSyncStatus is the part of the op-node optimism_syncStatus response used by the watcher
+type opSyncStatus struct {
+	UnsafeL2    opL2BlockRef `json:"unsafe_l2"`
+	SafeL2      opL2BlockRef `json:"safe_l2"`
+	FinalizedL2 opL2BlockRef `json:"finalized_l2"`
+}
+
+type opL2BlockRef struct {
+	Hash   common.Hash `json:"hash"`
+	Number uint64      `json:"number"`
+}
+
+// watchSyncStatus polls the sync status of the op-node and ensures that the safe and finalized
+// L2 heads advance within their windows. The lag between the unsafe and safe heads is reported
+// every time the unsafe head advances.
+func watchSyncStatus(out *watchdogOutput, opNodeURL string) error {
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchSyncStatus").WithField("op-node", opNodeURL)
+	log.Logger.Out = out
+
+	rpcClient, err := rpc.Dial(opNodeURL)
+	if err != nil {
+		return err
+	}
+	defer rpcClient.Close()
+
+	safeWindow := out.Duration("safe-head", "window")
+	finalizedWindow := out.Duration("finalized-head", "window")
+
+	// the heads are expected to advance within the windows since the start of the watchdog
+	var unsafe, safe, finalized uint64
+	safeTime, finalizedTime := time.Now(), time.Now()
+
+	for {
+		time.Sleep(2 * time.Second)
+
+		var status opSyncStatus
+		if err := rpcClient.CallContext(context.Background(), &status, "optimism_syncStatus"); err != nil {
+			return fmt.Errorf("failed to get the sync status: %w", err)
+		}
+		now := time.Now()
+
+		if status.UnsafeL2.Number > unsafe {
+			unsafe = status.UnsafeL2.Number
+			lag := unsafe - status.SafeL2.Number
+			log.Infof("Unsafe: %d, safe: %d (lag %d), finalized: %d", unsafe, status.SafeL2.Number, lag, status.FinalizedL2.Number)
+			out.Pass("safe-lag", lag, nil, "unsafe head %d, safe head %d", unsafe, status.SafeL2.Number)
+		}
+
+		if status.SafeL2.Number > safe {
+			out.Pass("safe-head", now.Sub(safeTime), safeWindow, "safe head %d", status.SafeL2.Number)
+			safe, safeTime = status.SafeL2.Number, now
+		} else if elapsed := now.Sub(safeTime); elapsed > safeWindow {
+			return out.Fail("safe-head", elapsed, safeWindow, "safe head stuck at %d for %s, the batches are not derived from L1", safe, elapsed.Round(time.Second))
+		}
+
+		if status.FinalizedL2.Number > finalized {
+			out.Pass("finalized-head", now.Sub(finalizedTime), finalizedWindow, "finalized head %d", status.FinalizedL2.Number)
+			finalized, finalizedTime = status.FinalizedL2.Number, now
+		} else if elapsed := now.Sub(finalizedTime); elapsed > finalizedWindow {
+			return out.Fail("finalized-head", elapsed, finalizedWindow, "finalized head stuck at %d for %s", finalized, elapsed.Round(time.Second))
+		}
+	}
+}
+
+// watchBatches follows the L1 blocks and ensures that the batcher posts a batch to the batch inbox
+// within the window. The time between the batches and the kind of data (blobs or calldata) of every
+// batch are reported.
+func watchBatches(out *watchdogOutput, l1URL string, batcher, inbox common.Address, window time.Duration) error {
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchBatches").WithField("inbox", inbox.Hex())
+	log.Logger.Out = out
+
+	rpcClient, err := rpc.Dial(l1URL)
+	if err != nil {
+		return err
+	}
+	clt := ethclient.NewClient(rpcClient)
+	defer clt.Close()
+
+	chainID, err := clt.ChainID(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get the chain id: %w", err)
+	}
+	signer := types.LatestSignerForChainID(chainID)
+
+	lastBlock, err := clt.BlockNumber(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get the block number: %w", err)
+	}
+	lastBatch := time.Now()
+	var blobBatches, calldataBatches uint64
+
+	for {
+		time.Sleep(2 * time.Second)
+
+		num, err := clt.BlockNumber(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to get the block number: %w", err)
+		}
+		for ; lastBlock < num; lastBlock++ {
+			block, err := clt.BlockByNumber(context.Background(), new(big.Int).SetUint64(lastBlock+1))
+			if err != nil {
+				return fmt.Errorf("failed to get block %d: %w", lastBlock+1, err)
+			}
+			for _, tx := range block.Transactions() {
+				if tx.To() == nil || *tx.To() != inbox {
+					continue
+				}
+				// the derivation only accepts the batches of the batcher in the system config
+				if sender, err := types.Sender(signer, tx); err != nil || sender != batcher {
+					continue
+				}
+
+				var kind string
+				if tx.Type() == types.BlobTxType {
+					blobBatches++
+					kind = fmt.Sprintf("blob batch (%d blobs)", len(tx.BlobHashes()))
+				} else {
+					calldataBatches++
+					kind = fmt.Sprintf("calldata batch (%d bytes)", len(tx.Data()))
+				}
+				now := time.Now()
+				log.Infof("Batch in L1 block %d: %s, %s since the last batch", block.NumberU64(), kind, now.Sub(lastBatch).Round(time.Second))
+				out.Pass("batches", now.Sub(lastBatch), window, "%s in L1 block %d (%d blob and %d calldata batches)", kind, block.NumberU64(), blobBatches, calldataBatches)
+				lastBatch = now
+			}
+		}
+
+		if elapsed := time.Since(lastBatch); elapsed > window {
+			return out.Fail("batches", elapsed, window, "no batch posted to the batch inbox in the last %s", elapsed.Round(time.Second))
+		}
+	}
+}
+
+// beaconGet queries an endpoint of the beacon API and decodes the data field of the response.
+// It returns false if the resource is not found (i.e. the block of a missed slot).
+func beaconGet(beaconNodeURL string, path string, data interface{}) (bool, error) {
+	resp, err := http.Get(beaconNodeURL + path)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status code %d for %s", resp.StatusCode, path)
+	}
+	obj := struct {
+		Data interface{} `json:"data"`
+	}{Data: data}
+	if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+		return false, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// watchFinality follows the slots of the beacon chain and ensures that the slots are not missed
+// and that the chain finalizes. It only uses the standard beacon API, except for the attestation
+// participation that is reported for every epoch if participation is set (lighthouse only).
+func watchFinality(out *watchdogOutput, beaconNodeURL string, participation bool) error {
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchFinality").WithField("beacon", beaconNodeURL)
+	log.Logger.Out = out
+
+	var genesis struct {
+		GenesisTime string `json:"genesis_time"`
+	}
+	if _, err := beaconGet(beaconNodeURL, "/eth/v1/beacon/genesis", &genesis); err != nil {
+		return fmt.Errorf("failed to get the genesis: %w", err)
+	}
+	var spec struct {
+		SecondsPerSlot string `json:"SECONDS_PER_SLOT"`
+		SlotsPerEpoch  string `json:"SLOTS_PER_EPOCH"`
+	}
+	if _, err := beaconGet(beaconNodeURL, "/eth/v1/config/spec", &spec); err != nil {
+		return fmt.Errorf("failed to get the spec: %w", err)
+	}
+	var genesisTime, secondsPerSlot, slotsPerEpoch uint64
+	for _, val := range []struct {
+		dst *uint64
+		src string
+	}{
+		{&genesisTime, genesis.GenesisTime},
+		{&secondsPerSlot, spec.SecondsPerSlot},
+		{&slotsPerEpoch, spec.SlotsPerEpoch},
+	} {
+		if _, err := fmt.Sscan(val.src, val.dst); err != nil {
+			return fmt.Errorf("failed to parse the beacon chain config '%s': %w", val.src, err)
+		}
+	}
+
+	maxMissed := out.Uint("missed-slots", "max-per-epoch")
+	maxEpochs := out.Uint("finality", "max-epochs")
+
+	currentSlot := func() uint64 {
+		now := uint64(time.Now().Unix())
+		if now < genesisTime {
+			return 0
+		}
+		return (now - genesisTime) / secondsPerSlot
+	}
+
+	// only the slots after the start of the watchdog are checked
+	lastSlot := currentSlot()
+	lastEpoch := lastSlot / slotsPerEpoch
+	var missed uint64
+
+	for {
+		time.Sleep(time.Duration(secondsPerSlot) * time.Second / 2)
+
+		// a slot is checked once the next one starts so that its block had time to propagate
+		slot := currentSlot()
+		for ; lastSlot+1 < slot; lastSlot++ {
+			checked := lastSlot + 1
+			var header struct {
+				Root string `json:"root"`
+			}
+			found, err := beaconGet(beaconNodeURL, fmt.Sprintf("/eth/v1/beacon/headers/%d", checked), &header)
+			if err != nil {
+				return fmt.Errorf("failed to get the block of slot %d: %w", checked, err)
+			}
+			if !found {
+				log.Infof("Missed slot %d", checked)
+				missed++
+			}
+
+			if (checked+1)%slotsPerEpoch == 0 {
+				epoch := checked / slotsPerEpoch
+				if missed > maxMissed {
+					return out.Fail("missed-slots", missed, maxMissed, "%d slots missed in epoch %d", missed, epoch)
+				}
+				out.Pass("missed-slots", missed, maxMissed, "%d slots missed in epoch %d", missed, epoch)
+				missed = 0
+			}
+		}
+
+		epoch := slot / slotsPerEpoch
+		if epoch == lastEpoch {
+			continue
+		}
+		lastEpoch = epoch
+
+		var checkpoints struct {
+			CurrentJustified struct {
+				Epoch string `json:"epoch"`
+			} `json:"current_justified"`
+			Finalized struct {
+				Epoch string `json:"epoch"`
+			} `json:"finalized"`
+		}
+		if _, err := beaconGet(beaconNodeURL, "/eth/v1/beacon/states/head/finality_checkpoints", &checkpoints); err != nil {
+			return fmt.Errorf("failed to get the finality checkpoints: %w", err)
+		}
+		var justified, finalized uint64
+		if _, err := fmt.Sscan(checkpoints.CurrentJustified.Epoch, &justified); err != nil {
+			return fmt.Errorf("failed to parse the justified epoch: %w", err)
+		}
+		if _, err := fmt.Sscan(checkpoints.Finalized.Epoch, &finalized); err != nil {
+			return fmt.Errorf("failed to parse the finalized epoch: %w", err)
+		}
+		log.Infof("Epoch %d: justified %d, finalized %d", epoch, justified, finalized)
+		if epoch-finalized > maxEpochs {
+			return out.Fail("finality", epoch-finalized, maxEpochs, "no finality for %d epochs, epoch %d and finalized epoch %d", epoch-finalized, epoch, finalized)
+		}
+		out.Pass("finality", epoch-finalized, maxEpochs, "epoch %d, justified epoch %d, finalized epoch %d", epoch, justified, finalized)
+
+		// the lighthouse endpoint computes the attestations of the previous epoch
+		// at the end of the requested epoch
+		if !participation || epoch < 2 {
+			continue
+		}
+		var inclusion struct {
+			CurrentEpochActiveGwei           uint64 `json:"current_epoch_active_gwei"`
+			PreviousEpochTargetAttestingGwei uint64 `json:"previous_epoch_target_attesting_gwei"`
+		}
+		if _, err := beaconGet(beaconNodeURL, fmt.Sprintf("/lighthouse/validator_inclusion/%d/global", epoch-1), &inclusion); err != nil {
+			log.Warnf("Failed to get the attestation participation: %v", err)
+			continue
+		}
+		if inclusion.CurrentEpochActiveGwei != 0 {
+			rate := float64(inclusion.PreviousEpochTargetAttestingGwei) / float64(inclusion.CurrentEpochActiveGwei)
+			log.Infof("Epoch %d: attestation participation %.2f%%", epoch-2, rate*100)
+			out.Pass("participation", rate, nil, "attestation participation of epoch %d", epoch-2)
 		}
 	}
 }
@@ -226,3 +969,81 @@ func (wg *watchGroup) watch(watch func() error) {
 func (wg *watchGroup) wait() error {
 	return <-wg.errCh
 }
+
+// fetchEnode returns the enode of the execution client with the admin_nodeInfo RPC method
+func fetchEnode(elURL string) (string, error) {
+	rpcClient, err := rpc.Dial(elURL)
+	if err != nil {
+		return "", err
+	}
+	defer rpcClient.Close()
+
+	var nodeInfo struct {
+		Enode string `json:"enode"`
+	}
+	if err := rpcClient.CallContext(context.Background(), &nodeInfo, "admin_nodeInfo"); err != nil {
+		return "", err
+	}
+	return nodeInfo.Enode, nil
+}
+
+// fetchBeaconENR returns the enr of the beacon node from the node identity endpoint
+func fetchBeaconENR(beaconNodeURL string) (string, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/eth/v1/node/identity", beaconNodeURL))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var identity struct {
+		Data struct {
+			ENR string `json:"enr"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&identity); err != nil {
+		return "", err
+	}
+	return identity.Data.ENR, nil
+}
+
+// addTrustedPeer adds the enode as a trusted peer of the execution client and connects to it
+func addTrustedPeer(elURL, enode string) error {
+	rpcClient, err := rpc.Dial(elURL)
+	if err != nil {
+		return err
+	}
+	defer rpcClient.Close()
+
+	for _, method := range []string{"admin_addTrustedPeer", "admin_addPeer"} {
+		var added bool
+		if err := rpcClient.CallContext(context.Background(), &added, method, enode); err != nil {
+			return fmt.Errorf("%s failed: %w", method, err)
+		}
+		if !added {
+			return fmt.Errorf("%s did not add the peer %s", method, enode)
+		}
+	}
+	return nil
+}
+
+// addBeaconPeer connects the beacon node to the enr with the lighthouse add peer endpoint
+func addBeaconPeer(beaconNodeURL, enr string) error {
+	body, err := json.Marshal(map[string]string{"enr": enr})
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(fmt.Sprintf("%s/lighthouse/add_peer", beaconNodeURL), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
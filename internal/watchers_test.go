@@ -0,0 +1,240 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestWatchdogOutput returns a watchdog output for the service that discards the logs
+func newTestWatchdogOutput(t *testing.T, service string, config *WatchdogConfig) (*watchdogOutput, *Watchdog) {
+	wd := NewWatchdog(&output{dst: t.TempDir()}, config)
+	return &watchdogOutput{Writer: io.Discard, service: service, watchdog: wd}, wd
+}
+
+// jsonRPCServer serves the json-rpc methods with the results of the handler
+func jsonRPCServer(t *testing.T, handler func(method string, params []interface{}) interface{}) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []interface{}   `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  handler(req.Method, req.Params),
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWatchRelayAudit(t *testing.T) {
+	blockHash := func(num uint64) string {
+		return fmt.Sprintf("0x%064x", num)
+	}
+
+	var payloadCalls atomic.Int64
+	relay := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "proposer_payload_delivered") {
+			// the second poll fails, the audit has to resume in the next one
+			calls := payloadCalls.Add(1)
+			if calls == 2 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			payloads := []map[string]string{
+				{"slot": "1", "block_number": "1", "block_hash": blockHash(1), "builder_pubkey": "0xb0", "value": "10"},
+			}
+			if calls > 3 {
+				// the payload of slot 2 is not the block of the el
+				payloads = append(payloads, map[string]string{"slot": "2", "block_number": "2", "block_hash": blockHash(200), "builder_pubkey": "0xb1", "value": "20"})
+			}
+			json.NewEncoder(w).Encode(payloads)
+			return
+		}
+
+		if r.URL.Query().Get("limit") != fmt.Sprint(relayBidsLimit) {
+			t.Errorf("expected an explicit limit, got %s", r.URL.RawQuery)
+		}
+		bids := []map[string]string{}
+		count := 2
+		if r.URL.Query().Get("slot") == "1" {
+			count = relayBidsLimit
+		}
+		for i := 0; i < count; i++ {
+			bids = append(bids, map[string]string{"slot": r.URL.Query().Get("slot"), "builder_pubkey": fmt.Sprintf("0xb%d", i%2), "value": fmt.Sprint(i)})
+		}
+		json.NewEncoder(w).Encode(bids)
+	}))
+	defer relay.Close()
+
+	// the el reaches block 2 after the first check
+	var blockNumberCalls atomic.Int64
+	el := jsonRPCServer(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "eth_blockNumber":
+			return fmt.Sprintf("0x%x", min(blockNumberCalls.Add(1), 2))
+		case "eth_getBlockByNumber":
+			var num uint64
+			fmt.Sscanf(params[0].(string), "0x%x", &num)
+			return map[string]string{"hash": blockHash(num)}
+		}
+		return nil
+	})
+
+	out, wd := newTestWatchdogOutput(t, "mev-boost", nil)
+	err := watchRelayAudit(out, relay.URL, el.URL)
+	if err == nil || !strings.Contains(err.Error(), "payload of slot 2") {
+		t.Fatalf("expected the payload of slot 2 to be not canonical, got %v", err)
+	}
+
+	report := wd.Report()
+	if len(report.Failures) != 1 || report.Failures[0].Check != "canonical-payloads" {
+		t.Fatalf("unexpected failures %+v", report.Failures)
+	}
+
+	data, err := os.ReadFile(filepath.Join(wd.out.dst, "relay-stats.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats struct {
+		Builders map[string]*relayBuilderAudit `json:"builders"`
+		Slots    []*relaySlotAudit             `json:"slots"`
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		t.Fatal(err)
+	}
+	// the stats are written after the check of slot 1, the audit fails before writing the ones of slot 2
+	if len(stats.Slots) != 1 || !stats.Slots[0].Truncated || stats.Slots[0].Winner != "0xb0" {
+		t.Fatalf("expected slot 1 to be truncated, got %+v", stats.Slots[0])
+	}
+	if b0 := stats.Builders["0xb0"]; b0 == nil || b0.Wins != 1 || b0.SlotsBid != 1 || b0.BlocksReceived != relayBidsLimit/2 {
+		t.Fatalf("unexpected stats of builder 0xb0 %+v", b0)
+	}
+}
+
+func TestWatchFinality(t *testing.T) {
+	for _, participation := range []bool{true, false} {
+		t.Run(fmt.Sprintf("participation=%t", participation), func(t *testing.T) {
+			t.Parallel()
+
+			// the chain starts 10 slots (5 epochs) before the watchdog, the finalized epoch
+			// does not move after the first check and odd slots are missed
+			genesisTime := time.Now().Unix() - 10
+			var checkpointCalls, inclusionCalls atomic.Int64
+			beacon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var data interface{}
+				switch path := r.URL.Path; {
+				case path == "/eth/v1/beacon/genesis":
+					data = map[string]string{"genesis_time": fmt.Sprint(genesisTime)}
+				case path == "/eth/v1/config/spec":
+					data = map[string]string{"SECONDS_PER_SLOT": "1", "SLOTS_PER_EPOCH": "2"}
+				case strings.HasPrefix(path, "/eth/v1/beacon/headers/"):
+					var slot uint64
+					fmt.Sscan(strings.TrimPrefix(path, "/eth/v1/beacon/headers/"), &slot)
+					if slot%2 == 1 {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					data = map[string]string{"root": fmt.Sprintf("0x%064x", slot)}
+				case path == "/eth/v1/beacon/states/head/finality_checkpoints":
+					// the first check is in epoch 6
+					finalized := 4
+					if checkpointCalls.Add(1) > 1 {
+						finalized = 0
+					}
+					data = map[string]map[string]string{
+						"current_justified": {"epoch": fmt.Sprint(finalized)},
+						"finalized":         {"epoch": fmt.Sprint(finalized)},
+					}
+				case strings.HasPrefix(path, "/lighthouse/validator_inclusion/"):
+					inclusionCalls.Add(1)
+					data = map[string]uint64{"current_epoch_active_gwei": 100, "previous_epoch_target_attesting_gwei": 90}
+				default:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+			}))
+			defer beacon.Close()
+
+			out, wd := newTestWatchdogOutput(t, "beacon", &WatchdogConfig{Checks: map[string]map[string]interface{}{
+				"missed-slots": {"max-per-epoch": uint64(1)},
+				"finality":     {"max-epochs": uint64(2)},
+			}})
+			err := watchFinality(out, beacon.URL, participation)
+			if err == nil || !strings.Contains(err.Error(), "no finality") {
+				t.Fatalf("expected the chain to not finalize, got %v", err)
+			}
+
+			events := map[string]uint64{}
+			for _, check := range wd.Report().Checks {
+				events[check.Check] = check.Events
+			}
+			if events["finality"] != 2 || events["missed-slots"] == 0 {
+				t.Fatalf("unexpected checks %v", events)
+			}
+			// the participation is only queried from the lighthouse endpoint if enabled
+			if participation && (inclusionCalls.Load() != 1 || events["participation"] != 1) {
+				t.Fatalf("expected the participation of one epoch, got %d calls and %v", inclusionCalls.Load(), events)
+			}
+			if !participation && (inclusionCalls.Load() != 0 || events["participation"] != 0) {
+				t.Fatalf("expected no participation, got %d calls and %v", inclusionCalls.Load(), events)
+			}
+		})
+	}
+}
+
+func TestWatchFlashblocks(t *testing.T) {
+	// the blocks 1 and 2 are built in 4 flashblocks and the block 3 in a single one,
+	// the block 4 ends the block 3
+	indexes := map[uint64]uint64{1: 4, 2: 4, 3: 1, 4: 1}
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for block := uint64(1); block <= 4; block++ {
+			for index := uint64(0); index < indexes[block]; index++ {
+				msg := map[string]interface{}{"index": index, "metadata": map[string]uint64{"block_number": block}}
+				if err := conn.WriteJSON(msg); err != nil {
+					return
+				}
+			}
+		}
+		// keep the connection open until the watcher is done
+		conn.ReadMessage()
+	}))
+	defer srv.Close()
+
+	out, wd := newTestWatchdogOutput(t, "flashblocks-proxy", nil)
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	err := watchFlashblocks(out, wsURL, time.Second, 250*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "block 3 built in 1 flashblocks, expected 4") {
+		t.Fatalf("expected the block 3 to miss flashblocks, got %v", err)
+	}
+
+	report := wd.Report()
+	if len(report.Checks) != 1 || report.Checks[0].Events != 3 || report.Checks[0].Failures != 1 {
+		t.Fatalf("unexpected checks %+v", report.Checks)
+	}
+}
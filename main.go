@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -20,6 +21,7 @@ var outputFlag string
 var genesisDelayFlag uint64
 var withOverrides []string
 var watchdog bool
+var watchdogConfigFile string
 var dryRun bool
 var interactive bool
 var timeout time.Duration
@@ -30,7 +32,21 @@ var networkName string
 var labels internal.MapStringFlag
 var withGrafanaAlloy bool
 var withCaddy []string
+var withExplorer bool
 var detach bool
+var sessionFlag string
+var followLogs bool
+var recipeFile string
+var runnerFlag string
+var k8sNamespace string
+var k8sRenderOnly bool
+var runtimeFlag string
+var genesisTimeFlag string
+var seedFlag string
+var mnemonicFlag string
+var prefundedCount int
+var prefundedBalance string
+var allocFile string
 
 var rootCmd = &cobra.Command{
 	Use:   "playground",
@@ -45,11 +61,19 @@ var cookCmd = &cobra.Command{
 	Use:   "cook",
 	Short: "Cook a recipe",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if recipeFile != "" {
+			recipe, err := internal.LoadRecipeFile(recipeFile)
+			if err != nil {
+				return err
+			}
+			return runIt(recipe)
+		}
+
 		recipeNames := []string{}
 		for _, recipe := range recipes {
 			recipeNames = append(recipeNames, recipe.Name())
 		}
-		return fmt.Errorf("please specify a recipe to cook. Available recipes: %s", recipeNames)
+		return fmt.Errorf("please specify a recipe to cook (or a file with --recipe-file). Available recipes: %s", recipeNames)
 	},
 }
 
@@ -147,6 +171,55 @@ var inspectCmd = &cobra.Command{
 	},
 }
 
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the running playground sessions and the state of their services",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return internal.SessionStatus(os.Stdout)
+	},
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <service>",
+	Short: "Show the logs of a service in a playground session",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("please specify a service name")
+		}
+
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-sig
+			cancel()
+		}()
+
+		return internal.SessionLogs(ctx, os.Stdout, sessionFlag, args[0], followLogs)
+	},
+}
+
+var stopCmd = &cobra.Command{
+	Use:   "stop [session]",
+	Short: "Stop a playground session (defaults to the most recent one)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 1 {
+			return fmt.Errorf("expected at most one session id")
+		}
+		var sessionID string
+		if len(args) == 1 {
+			sessionID = args[0]
+		}
+		session, err := internal.StopSession(sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to stop session: %w", err)
+		}
+		fmt.Printf("Session %s stopped\n", session.ID)
+		return nil
+	},
+}
+
 var recipes = []internal.Recipe{
 	&internal.L1Recipe{},
 	&internal.OpRecipe{},
@@ -166,33 +239,28 @@ func main() {
 		// add the flags from the recipe
 		recipeCmd.Flags().AddFlagSet(recipe.Flags())
 		// add the common flags
-		recipeCmd.Flags().StringVar(&outputFlag, "output", "", "Output folder for the artifacts")
-		recipeCmd.Flags().BoolVar(&watchdog, "watchdog", false, "enable watchdog")
-		recipeCmd.Flags().StringArrayVar(&withOverrides, "override", []string{}, "override a service's config")
-		recipeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "dry run the recipe")
-		recipeCmd.Flags().BoolVar(&dryRun, "mise-en-place", false, "mise en place mode")
-		recipeCmd.Flags().Uint64Var(&genesisDelayFlag, "genesis-delay", internal.MinimumGenesisDelay, "")
-		recipeCmd.Flags().BoolVar(&interactive, "interactive", false, "interactive mode")
-		recipeCmd.Flags().DurationVar(&timeout, "timeout", 0, "") // Used for CI
-		recipeCmd.Flags().StringVar(&logLevelFlag, "log-level", "info", "log level")
-		recipeCmd.Flags().BoolVar(&bindExternal, "bind-external", false, "bind host ports to external interface")
-		recipeCmd.Flags().BoolVar(&withPrometheus, "with-prometheus", false, "whether to gather the Prometheus metrics")
-		recipeCmd.Flags().BoolVar(&withGrafanaAlloy, "with-grafana-alloy", false, "whether to spawn a grafana alloy to agent for metrics, logs, traces")
-		recipeCmd.Flags().StringArrayVar(&withCaddy, "with-caddy", []string{}, "Enable caddy and expose the services with the given names")
-		recipeCmd.Flags().StringVar(&networkName, "network", "", "network name")
-		recipeCmd.Flags().BoolVar(&detach, "detach", false, "detach the services")
-		recipeCmd.Flags().Var(&labels, "labels", "list of labels to apply to the resources")
+		addCommonFlags(recipeCmd)
 		cookCmd.AddCommand(recipeCmd)
 	}
 
+	// cook can also run a recipe described in a file
+	cookCmd.Flags().StringVar(&recipeFile, "recipe-file", "", "path to a YAML or TOML recipe file")
+	addCommonFlags(cookCmd)
+
 	// reuse the same output flag for the artifacts command
 	artifactsCmd.Flags().StringVar(&outputFlag, "output", "", "Output folder for the artifacts")
 	artifactsAllCmd.Flags().StringVar(&outputFlag, "output", "", "Output folder for the artifacts")
 
+	logsCmd.Flags().StringVar(&sessionFlag, "session", "", "session id (defaults to the most recent session)")
+	logsCmd.Flags().BoolVarP(&followLogs, "follow", "f", false, "follow the log output")
+
 	rootCmd.AddCommand(cookCmd)
 	rootCmd.AddCommand(artifactsCmd)
 	rootCmd.AddCommand(artifactsAllCmd)
 	rootCmd.AddCommand(inspectCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(stopCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -200,6 +268,72 @@ func main() {
 	}
 }
 
+func addCommonFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&outputFlag, "output", "", "Output folder for the artifacts")
+	cmd.Flags().BoolVar(&watchdog, "watchdog", false, "enable watchdog")
+	cmd.Flags().StringVar(&watchdogConfigFile, "watchdog-config", "", "JSON or YAML file with the thresholds of the watchdog checks")
+	cmd.Flags().StringArrayVar(&withOverrides, "override", []string{}, "override a service's config")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "dry run the recipe")
+	cmd.Flags().BoolVar(&dryRun, "mise-en-place", false, "mise en place mode")
+	cmd.Flags().Uint64Var(&genesisDelayFlag, "genesis-delay", internal.MinimumGenesisDelay, "")
+	cmd.Flags().StringVar(&genesisTimeFlag, "genesis-time", "", "fixed genesis time, either a unix timestamp or an offset in seconds from the pinned epoch (i.e. +3600)")
+	cmd.Flags().StringVar(&seedFlag, "seed", "", "seed to generate reproducible artifacts (requires --genesis-time)")
+	cmd.Flags().StringVar(&mnemonicFlag, "mnemonic", "", "BIP-39 mnemonic to derive extra prefunded accounts (m/44'/60'/0'/0/i)")
+	cmd.Flags().IntVar(&prefundedCount, "prefunded-count", 0, "number of prefunded accounts to derive from the mnemonic (defaults to 10 with --mnemonic)")
+	cmd.Flags().StringVar(&prefundedBalance, "prefunded-balance", "", "balance in wei (decimal or 0x hex) of each prefunded account")
+	cmd.Flags().StringVar(&allocFile, "alloc-file", "", "JSON file with extra accounts (address, balance, code, storage, nonce) for the L1 and L2 genesis")
+	cmd.Flags().BoolVar(&interactive, "interactive", false, "interactive mode")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "") // Used for CI
+	cmd.Flags().StringVar(&logLevelFlag, "log-level", "info", "log level")
+	cmd.Flags().BoolVar(&bindExternal, "bind-external", false, "bind host ports to external interface")
+	cmd.Flags().BoolVar(&withPrometheus, "with-prometheus", false, "whether to gather the Prometheus metrics")
+	cmd.Flags().BoolVar(&withGrafanaAlloy, "with-grafana-alloy", false, "whether to spawn a grafana alloy to agent for metrics, logs, traces")
+	cmd.Flags().StringArrayVar(&withCaddy, "with-caddy", []string{}, "Enable caddy and expose the services with the given names")
+	cmd.Flags().BoolVar(&withExplorer, "with-explorer", false, "whether to spawn a Blockscout explorer for each execution layer")
+	cmd.Flags().StringVar(&networkName, "network", "", "network name")
+	cmd.Flags().BoolVar(&detach, "detach", false, "detach the services")
+	cmd.Flags().Var(&labels, "labels", "list of labels to apply to the resources")
+	cmd.Flags().StringVar(&runnerFlag, "runner", "local", "runner to deploy the services (local, docker, kubernetes)")
+	cmd.Flags().StringVar(&runtimeFlag, "runtime", "docker", "container runtime for the local runners (docker, podman)")
+	cmd.Flags().StringVar(&k8sNamespace, "k8s-namespace", "default", "namespace to deploy the services with the kubernetes runner")
+	cmd.Flags().BoolVar(&k8sRenderOnly, "k8s-render-only", false, "only render the kubernetes manifest in the output folder without applying it")
+}
+
+func newRunner(artifacts *internal.Artifacts, svcManager *internal.Manifest, overrides map[string]string) (internal.Runner, error) {
+	var runtime internal.ContainerRuntime
+	if err := runtime.Unmarshal(runtimeFlag); err != nil {
+		return nil, err
+	}
+	cfg := &internal.LocalRunnerConfig{
+		Out:                  artifacts.Out,
+		Manifest:             svcManager,
+		Overrides:            overrides,
+		Interactive:          interactive,
+		BindHostPortsLocally: !bindExternal,
+		NetworkName:          networkName,
+		Labels:               labels,
+		Runtime:              runtime,
+	}
+
+	switch runnerFlag {
+	case "local":
+		return internal.NewLocalRunner(cfg)
+	case "docker":
+		return internal.NewDockerRunner(cfg)
+	case "kubernetes":
+		if len(overrides) != 0 {
+			return nil, fmt.Errorf("overrides are not supported with the kubernetes runner")
+		}
+		if watchdog {
+			// the watchdogs query the services from the host machine
+			return nil, fmt.Errorf("--watchdog is not supported with the kubernetes runner")
+		}
+		return internal.NewKubernetesRunner(artifacts.Out, svcManager, k8sNamespace, k8sRenderOnly, labels)
+	default:
+		return nil, fmt.Errorf("unknown runner '%s', expected local, docker or kubernetes", runnerFlag)
+	}
+}
+
 func runIt(recipe internal.Recipe) error {
 	var logLevel internal.LogLevel
 	if err := logLevel.Unmarshal(logLevelFlag); err != nil {
@@ -218,9 +352,46 @@ func runIt(recipe internal.Recipe) error {
 		overrides[parts[0]] = parts[1]
 	}
 
+	if watchdog && detach {
+		// the watchdog runs in this process, it would stop without a report once the services are detached
+		return fmt.Errorf("--watchdog cannot be used with --detach")
+	}
+
+	var watchdogConfig *internal.WatchdogConfig
+	if watchdogConfigFile != "" {
+		if !watchdog {
+			return fmt.Errorf("--watchdog-config requires --watchdog")
+		}
+		var err error
+		if watchdogConfig, err = internal.LoadWatchdogConfig(watchdogConfigFile); err != nil {
+			return err
+		}
+	}
+
+	if validator, ok := recipe.(internal.RecipeValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return err
+		}
+	}
+
 	builder := recipe.Artifacts()
 	builder.OutputDir(outputFlag)
 	builder.GenesisDelay(genesisDelayFlag)
+	genesisTime, err := internal.ParseGenesisTime(genesisTimeFlag)
+	if err != nil {
+		return err
+	}
+	builder.GenesisTime(genesisTime)
+	builder.Seed(seedFlag)
+	builder.PrefundedAccounts(mnemonicFlag, prefundedCount)
+	builder.AllocFile(allocFile)
+	if prefundedBalance != "" {
+		balance, err := internal.ParseBalance(prefundedBalance)
+		if err != nil {
+			return err
+		}
+		builder.PrefundedBalance(balance)
+	}
 	artifacts, err := builder.Build()
 	if err != nil {
 		return err
@@ -240,6 +411,17 @@ func runIt(recipe internal.Recipe) error {
 		}
 	}
 
+	if withExplorer {
+		explorers, err := internal.CreateExplorerServices(svcManager, len(withCaddy) > 0)
+		if err != nil {
+			return fmt.Errorf("failed to create explorer services: %w", err)
+		}
+		if len(withCaddy) > 0 {
+			// the explorers are exposed with the rest of the caddy routes
+			withCaddy = append(withCaddy, explorers...)
+		}
+	}
+
 	if len(withCaddy) > 0 {
 		log.Printf("Spawning a caddy reverse proxy for the services: %v\n", withCaddy)
 		if err := internal.CreateCaddyServices(withCaddy, svcManager, artifacts.Out); err != nil {
@@ -247,6 +429,10 @@ func runIt(recipe internal.Recipe) error {
 		}
 	}
 
+	if err := svcManager.WriteConfigs(); err != nil {
+		return err
+	}
+
 	if err := svcManager.Validate(); err != nil {
 		return fmt.Errorf("failed to validate manifest: %w", err)
 	}
@@ -278,9 +464,9 @@ func runIt(recipe internal.Recipe) error {
 		}
 	}
 
-	dockerRunner, err := internal.NewLocalRunner(artifacts.Out, svcManager, overrides, interactive, !bindExternal, networkName, labels)
+	runner, err := newRunner(artifacts, svcManager, overrides)
 	if err != nil {
-		return fmt.Errorf("failed to create docker runner: %w", err)
+		return fmt.Errorf("failed to create runner: %w", err)
 	}
 
 	sig := make(chan os.Signal, 1)
@@ -292,9 +478,18 @@ func runIt(recipe internal.Recipe) error {
 		cancel()
 	}()
 
-	if err := dockerRunner.Run(); err != nil {
-		dockerRunner.Stop()
-		return fmt.Errorf("failed to run docker: %w", err)
+	if err := runner.Run(); err != nil {
+		runner.Stop()
+		return fmt.Errorf("failed to run: %w", err)
+	}
+
+	if runnerFlag == "kubernetes" && k8sRenderOnly {
+		outputDir, err := artifacts.Out.AbsoluteDstPath()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Kubernetes manifest written to %s\n", filepath.Join(outputDir, "kubernetes.yaml"))
+		return nil
 	}
 
 	if !interactive {
@@ -332,13 +527,13 @@ func runIt(recipe internal.Recipe) error {
 		}
 	}
 
-	if err := dockerRunner.WaitForReady(ctx, 20*time.Second); err != nil {
-		dockerRunner.Stop()
+	if err := runner.WaitForReady(ctx, 20*time.Second); err != nil {
+		runner.Stop()
 		return fmt.Errorf("failed to wait for service readiness: %w", err)
 	}
 
-	if err := internal.CompleteReady(dockerRunner.Instances()); err != nil {
-		dockerRunner.Stop()
+	if err := internal.CompleteReady(runner.Instances()); err != nil {
+		runner.Stop()
 		return fmt.Errorf("failed to complete ready: %w", err)
 	}
 
@@ -352,9 +547,11 @@ func runIt(recipe internal.Recipe) error {
 	}
 
 	watchdogErr := make(chan error, 1)
+	var wd *internal.Watchdog
 	if watchdog {
+		wd = internal.NewWatchdog(artifacts.Out, watchdogConfig)
 		go func() {
-			if err := internal.RunWatchdog(artifacts.Out, dockerRunner.Instances()); err != nil {
+			if err := wd.Run(runner.Instances()); err != nil {
 				watchdogErr <- fmt.Errorf("watchdog failed: %w", err)
 			}
 		}()
@@ -372,7 +569,7 @@ func runIt(recipe internal.Recipe) error {
 	select {
 	case <-ctx.Done():
 		fmt.Println("Stopping...")
-	case err := <-dockerRunner.ExitErr():
+	case err := <-runner.ExitErr():
 		fmt.Println("Service failed:", err)
 	case err := <-watchdogErr:
 		fmt.Println("Watchdog failed:", err)
@@ -380,8 +577,21 @@ func runIt(recipe internal.Recipe) error {
 		fmt.Println("Timeout reached")
 	}
 
-	if err := dockerRunner.Stop(); err != nil {
-		return fmt.Errorf("failed to stop docker: %w", err)
+	if wd != nil {
+		report, err := wd.Close()
+		if err != nil {
+			fmt.Println("Failed to write the watchdog report:", err)
+		} else {
+			fmt.Printf("\n========= Watchdog =========\n")
+			fmt.Printf("- status: %s (%d failures)\n", report.Status, len(report.Failures))
+			for service, summary := range report.Summaries {
+				fmt.Printf("- %s: %v\n", service, summary)
+			}
+		}
+	}
+
+	if err := runner.Stop(); err != nil {
+		return fmt.Errorf("failed to stop: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	txspammer "github.com/phylaxsystems/builder-playground/tx-spammer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rpcURL         string
+	privateKeys    []string
+	tps            uint64
+	txTypes        []string
+	bundleRPCURL   string
+	bundleSize     int
+	reportInterval time.Duration
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "tx-spammer",
+	Short: "Send a constant load of transactions to an el node or a builder",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTxSpammer()
+	},
+}
+
+func main() {
+	cfg := txspammer.DefaultConfig()
+	rootCmd.Flags().StringVar(&rpcURL, "rpc", cfg.RPCURL, "el node to send the transactions to")
+	rootCmd.Flags().StringSliceVar(&privateKeys, "private-keys", nil, "private keys of the funded accounts")
+	rootCmd.Flags().Uint64Var(&tps, "tps", cfg.TPS, "transactions per second")
+	rootCmd.Flags().StringSliceVar(&txTypes, "tx-types", cfg.TxTypes, "types of transactions (legacy, dynamic, blob, call)")
+	rootCmd.Flags().StringVar(&bundleRPCURL, "bundle-rpc", "", "builder to send the transactions as bundles (eth_sendBundle)")
+	rootCmd.Flags().IntVar(&bundleSize, "bundle-size", cfg.BundleSize, "number of transactions of each bundle")
+	rootCmd.Flags().DurationVar(&reportInterval, "report-interval", cfg.ReportInterval, "interval to report the submitted and included counts")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func runTxSpammer() error {
+	cfg := txspammer.DefaultConfig()
+	cfg.RPCURL = rpcURL
+	cfg.PrivateKeys = privateKeys
+	cfg.TPS = tps
+	cfg.TxTypes = txTypes
+	cfg.BundleRPCURL = bundleRPCURL
+	cfg.BundleSize = bundleSize
+	cfg.ReportInterval = reportInterval
+
+	spammer, err := txspammer.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create tx spammer: %w", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	return spammer.Run(ctx)
+}
@@ -0,0 +1,606 @@
+package txspammer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	TxTypeLegacy  = "legacy"
+	TxTypeDynamic = "dynamic"
+	TxTypeBlob    = "blob"
+	TxTypeCall    = "call"
+)
+
+var TxTypes = []string{TxTypeLegacy, TxTypeDynamic, TxTypeBlob, TxTypeCall}
+
+// counterContract is the init code of a contract that increments the slot 0 on every call
+var counterContract = hexutil.MustDecode("0x600a600c600039600a6000f360005460010160005500")
+
+// pendingTimeoutBlocks is the number of blocks after which a submitted transaction
+// that is not included is reported as dropped
+const pendingTimeoutBlocks = 32
+
+type Config struct {
+	LogOutput io.Writer
+
+	// RPCURL is the el node to send the transactions to and to track the inclusion
+	RPCURL string
+
+	// PrivateKeys are the funded accounts that sign the transactions
+	PrivateKeys []string
+
+	// TPS is the number of transactions sent per second
+	TPS uint64
+
+	// TxTypes are the types of transactions to send, they are sent in round robin
+	TxTypes []string
+
+	// BundleRPCURL is the builder to send the transactions as bundles (eth_sendBundle)
+	// instead of sending them to the mempool of the el node
+	BundleRPCURL string
+
+	// BundleSize is the number of transactions of each bundle
+	BundleSize int
+
+	// ReportInterval is the interval to log the submitted and included counts
+	ReportInterval time.Duration
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		LogOutput:      os.Stdout,
+		RPCURL:         "http://localhost:8545",
+		TPS:            10,
+		TxTypes:        []string{TxTypeDynamic},
+		BundleSize:     5,
+		ReportInterval: 10 * time.Second,
+	}
+}
+
+type account struct {
+	key   *ecdsa.PrivateKey
+	addr  common.Address
+	nonce uint64
+}
+
+type Spammer struct {
+	config *Config
+	log    *logrus.Entry
+
+	client       *ethclient.Client
+	bundleClient *ethclient.Client
+	chainID      *big.Int
+	signer       types.Signer
+	allAccounts  []*account
+	accounts     []*account
+	blobAccounts []*account
+	sent         map[string]int
+	contract     common.Address
+	blobSidecar  *types.BlobTxSidecar
+
+	// fees and the block number are updated on every new block
+	lock        sync.Mutex
+	blockNumber uint64
+	baseFee     *big.Int
+	tipCap      *big.Int
+
+	// pending are the submitted transactions not included yet with the block of submission
+	pending   map[common.Hash]uint64
+	submitted uint64
+	included  uint64
+	dropped   uint64
+	failed    uint64
+}
+
+func New(config *Config) (*Spammer, error) {
+	if config.TPS == 0 {
+		return nil, fmt.Errorf("tps must be positive")
+	}
+	if len(config.PrivateKeys) == 0 {
+		return nil, fmt.Errorf("at least one private key is required")
+	}
+	if len(config.TxTypes) == 0 {
+		return nil, fmt.Errorf("at least one tx type is required")
+	}
+	for _, txType := range config.TxTypes {
+		if !slices.Contains(TxTypes, txType) {
+			return nil, fmt.Errorf("invalid tx type '%s', expected one of %s", txType, strings.Join(TxTypes, ", "))
+		}
+	}
+	if config.BundleRPCURL != "" && config.BundleSize <= 0 {
+		return nil, fmt.Errorf("bundle size must be positive")
+	}
+
+	log := logrus.NewEntry(logrus.New())
+	log.Logger.SetOutput(config.LogOutput)
+
+	accounts := []*account{}
+	for _, privStr := range config.PrivateKeys {
+		key, err := crypto.HexToECDSA(strings.TrimPrefix(privStr, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		accounts = append(accounts, &account{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)})
+	}
+
+	// the txpool does not accept blob and regular transactions from the same account,
+	// the blob transactions are sent from their own accounts
+	allAccounts, blobAccounts := accounts, accounts
+	if slices.Contains(config.TxTypes, TxTypeBlob) && len(config.TxTypes) > 1 {
+		if len(accounts) < 2 {
+			return nil, fmt.Errorf("at least two private keys are required to send blob and non-blob transactions")
+		}
+		split := len(accounts) / 2
+		accounts, blobAccounts = accounts[:split], accounts[split:]
+	}
+
+	return &Spammer{
+		config:       config,
+		log:          log,
+		allAccounts:  allAccounts,
+		accounts:     accounts,
+		blobAccounts: blobAccounts,
+		sent:         map[string]int{},
+		pending:      map[common.Hash]uint64{},
+	}, nil
+}
+
+// nextAccount returns the account of the next transaction of the type in round robin
+func (s *Spammer) nextAccount(txType string) *account {
+	accounts := s.accounts
+	if txType == TxTypeBlob {
+		accounts = s.blobAccounts
+	}
+	acct := accounts[s.sent[txType]%len(accounts)]
+	s.sent[txType]++
+	return acct
+}
+
+// Run sends transactions until the context is cancelled
+func (s *Spammer) Run(ctx context.Context) error {
+	var err error
+	if s.client, err = dialWithRetry(ctx, s.config.RPCURL); err != nil {
+		return err
+	}
+	if s.config.BundleRPCURL != "" {
+		// the builder only serves the bundle methods, the connection is checked on the first bundle
+		if s.bundleClient, err = ethclient.DialContext(ctx, s.config.BundleRPCURL); err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", s.config.BundleRPCURL, err)
+		}
+	}
+
+	if s.chainID, err = s.client.ChainID(ctx); err != nil {
+		return fmt.Errorf("failed to get chain id: %w", err)
+	}
+	s.signer = types.LatestSignerForChainID(s.chainID)
+
+	if err := s.syncNonces(ctx); err != nil {
+		return err
+	}
+	if err := s.updateHead(ctx); err != nil {
+		return err
+	}
+
+	for _, txType := range s.config.TxTypes {
+		switch txType {
+		case TxTypeCall:
+			if err := s.deployContract(ctx); err != nil {
+				return err
+			}
+		case TxTypeBlob:
+			if s.blobSidecar, err = newBlobSidecar(); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"rpc":      s.config.RPCURL,
+		"bundles":  s.config.BundleRPCURL,
+		"tps":      s.config.TPS,
+		"types":    strings.Join(s.config.TxTypes, ","),
+		"accounts": len(s.accounts),
+	}).Info("Starting the tx spammer")
+
+	go s.trackBlocks(ctx)
+	go s.report(ctx)
+
+	ticker := time.NewTicker(time.Second / time.Duration(s.config.TPS))
+	defer ticker.Stop()
+
+	bundle := []*types.Transaction{}
+	var noncesBlock uint64
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if s.bundleClient != nil {
+			// the bundles that were not included leave gaps in the nonces,
+			// start again from the nonces of the node on every new block
+			if head := s.head(); head != noncesBlock {
+				if err := s.syncNonces(ctx); err != nil {
+					s.log.WithError(err).Warn("Failed to sync the nonces")
+				}
+				noncesBlock = head
+				bundle = bundle[:0]
+			}
+		}
+
+		txType := s.config.TxTypes[i%len(s.config.TxTypes)]
+		acct := s.nextAccount(txType)
+		tx, err := s.buildTx(acct, txType)
+		if err != nil {
+			return err
+		}
+
+		if s.bundleClient == nil {
+			if err := s.client.SendTransaction(ctx, tx); err != nil {
+				s.log.WithError(err).WithField("from", acct.addr).Warn("Failed to send transaction")
+				s.recordFailed(1)
+				s.resyncNonce(ctx, acct)
+				continue
+			}
+			s.recordSubmitted(tx)
+			continue
+		}
+
+		bundle = append(bundle, tx)
+		if len(bundle) < s.config.BundleSize {
+			continue
+		}
+		if err := s.sendBundle(ctx, bundle); err != nil {
+			s.log.WithError(err).Warn("Failed to send bundle")
+			s.recordFailed(uint64(len(bundle)))
+		} else {
+			for _, tx := range bundle {
+				s.recordSubmitted(tx)
+			}
+		}
+		bundle = bundle[:0]
+	}
+}
+
+func dialWithRetry(ctx context.Context, url string) (*ethclient.Client, error) {
+	for {
+		client, err := ethclient.DialContext(ctx, url)
+		if err == nil {
+			if _, err = client.BlockNumber(ctx); err == nil {
+				return client, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to connect to %s: %w", url, err)
+		case <-time.After(1 * time.Second):
+		}
+	}
+}
+
+func (s *Spammer) syncNonces(ctx context.Context) error {
+	for _, acct := range s.allAccounts {
+		nonce, err := s.client.PendingNonceAt(ctx, acct.addr)
+		if err != nil {
+			return fmt.Errorf("failed to get the nonce of %s: %w", acct.addr, err)
+		}
+		acct.nonce = nonce
+	}
+	return nil
+}
+
+func (s *Spammer) resyncNonce(ctx context.Context, acct *account) {
+	if nonce, err := s.client.PendingNonceAt(ctx, acct.addr); err == nil {
+		acct.nonce = nonce
+	}
+}
+
+func (s *Spammer) updateHead(ctx context.Context) error {
+	header, err := s.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get the latest header: %w", err)
+	}
+	tipCap, err := s.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the gas tip cap: %w", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.blockNumber = header.Number.Uint64()
+	s.baseFee = header.BaseFee
+	if s.baseFee == nil {
+		s.baseFee = big.NewInt(params.InitialBaseFee)
+	}
+	s.tipCap = tipCap
+	return nil
+}
+
+func (s *Spammer) head() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.blockNumber
+}
+
+// fees returns the tip cap and the fee cap (twice the base fee plus the tip) of the next transactions
+func (s *Spammer) fees() (*big.Int, *big.Int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	feeCap := new(big.Int).Mul(s.baseFee, big.NewInt(2))
+	feeCap.Add(feeCap, s.tipCap)
+	return new(big.Int).Set(s.tipCap), feeCap
+}
+
+func (s *Spammer) buildTx(acct *account, txType string) (*types.Transaction, error) {
+	tipCap, feeCap := s.fees()
+
+	// the transfers are sent to the same account to keep the funds
+	to := acct.addr
+	value := big.NewInt(1)
+
+	var txData types.TxData
+	switch txType {
+	case TxTypeLegacy:
+		txData = &types.LegacyTx{
+			Nonce:    acct.nonce,
+			GasPrice: feeCap,
+			Gas:      params.TxGas,
+			To:       &to,
+			Value:    value,
+		}
+	case TxTypeDynamic:
+		txData = &types.DynamicFeeTx{
+			ChainID:   s.chainID,
+			Nonce:     acct.nonce,
+			GasTipCap: tipCap,
+			GasFeeCap: feeCap,
+			Gas:       params.TxGas,
+			To:        &to,
+			Value:     value,
+		}
+	case TxTypeCall:
+		contract := s.contract
+		txData = &types.DynamicFeeTx{
+			ChainID:   s.chainID,
+			Nonce:     acct.nonce,
+			GasTipCap: tipCap,
+			GasFeeCap: feeCap,
+			Gas:       60_000,
+			To:        &contract,
+		}
+	case TxTypeBlob:
+		txData = &types.BlobTx{
+			ChainID:    uint256.MustFromBig(s.chainID),
+			Nonce:      acct.nonce,
+			GasTipCap:  uint256.MustFromBig(tipCap),
+			GasFeeCap:  uint256.MustFromBig(feeCap),
+			Gas:        params.TxGas,
+			To:         to,
+			Value:      uint256.MustFromBig(value),
+			BlobFeeCap: uint256.NewInt(params.GWei),
+			BlobHashes: s.blobSidecar.BlobHashes(),
+			Sidecar:    s.blobSidecar,
+		}
+	default:
+		return nil, fmt.Errorf("invalid tx type '%s'", txType)
+	}
+
+	tx, err := types.SignNewTx(acct.key, s.signer, txData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	acct.nonce++
+	return tx, nil
+}
+
+// deployContract deploys the counter contract used by the contract call transactions
+func (s *Spammer) deployContract(ctx context.Context) error {
+	acct := s.accounts[0]
+	tipCap, feeCap := s.fees()
+
+	tx, err := types.SignNewTx(acct.key, s.signer, &types.DynamicFeeTx{
+		ChainID:   s.chainID,
+		Nonce:     acct.nonce,
+		GasTipCap: tipCap,
+		GasFeeCap: feeCap,
+		Gas:       100_000,
+		Data:      counterContract,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sign the contract deployment: %w", err)
+	}
+	if err := s.client.SendTransaction(ctx, tx); err != nil {
+		return fmt.Errorf("failed to deploy the contract: %w", err)
+	}
+	acct.nonce++
+
+	for {
+		receipt, err := s.client.TransactionReceipt(ctx, tx.Hash())
+		if err == nil {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return fmt.Errorf("the contract deployment %s failed", tx.Hash())
+			}
+			s.contract = receipt.ContractAddress
+			s.log.WithField("address", s.contract).Info("Contract deployed")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(1 * time.Second):
+		}
+	}
+}
+
+// newBlobSidecar returns the sidecar with a single blob shared by all the blob transactions
+func newBlobSidecar() (*types.BlobTxSidecar, error) {
+	var blob kzg4844.Blob
+	copy(blob[:], []byte("builder-playground tx spammer"))
+
+	commitment, err := kzg4844.BlobToCommitment(&blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute the blob commitment: %w", err)
+	}
+	proof, err := kzg4844.ComputeBlobProof(&blob, commitment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute the blob proof: %w", err)
+	}
+	return &types.BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{blob},
+		Commitments: []kzg4844.Commitment{commitment},
+		Proofs:      []kzg4844.Proof{proof},
+	}, nil
+}
+
+// sendBundle sends the transactions as a bundle for the next block
+func (s *Spammer) sendBundle(ctx context.Context, txs []*types.Transaction) error {
+	rawTxs := []hexutil.Bytes{}
+	for _, tx := range txs {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		rawTxs = append(rawTxs, raw)
+	}
+
+	targetBlock := s.head() + 1
+
+	var result interface{}
+	return s.bundleClient.Client().CallContext(ctx, &result, "eth_sendBundle", map[string]interface{}{
+		"txs":         rawTxs,
+		"blockNumber": hexutil.Uint64(targetBlock),
+	})
+}
+
+// trackBlocks polls the new blocks to count the included transactions
+func (s *Spammer) trackBlocks(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		lastBlock := s.head()
+		latest, err := s.client.BlockNumber(ctx)
+		if err != nil || latest <= lastBlock {
+			continue
+		}
+		for num := lastBlock + 1; num <= latest; num++ {
+			block, err := s.client.BlockByNumber(ctx, new(big.Int).SetUint64(num))
+			if err != nil {
+				s.log.WithError(err).WithField("block", num).Warn("Failed to get block")
+				break
+			}
+			s.recordBlock(block)
+		}
+		if err := s.updateHead(ctx); err != nil {
+			s.log.WithError(err).Warn("Failed to update the head")
+		}
+	}
+}
+
+func (s *Spammer) recordSubmitted(tx *types.Transaction) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.submitted++
+	s.pending[tx.Hash()] = s.blockNumber
+}
+
+func (s *Spammer) recordFailed(count uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.failed += count
+}
+
+func (s *Spammer) recordBlock(block *types.Block) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, tx := range block.Transactions() {
+		if _, ok := s.pending[tx.Hash()]; ok {
+			delete(s.pending, tx.Hash())
+			s.included++
+		}
+	}
+	for hash, submittedAt := range s.pending {
+		if block.NumberU64() > submittedAt+pendingTimeoutBlocks {
+			delete(s.pending, hash)
+			s.dropped++
+		}
+	}
+}
+
+// Stats are the counts of the transactions sent by the spammer
+type Stats struct {
+	Submitted uint64
+	Included  uint64
+	Pending   uint64
+	Dropped   uint64
+	Failed    uint64
+}
+
+func (s *Spammer) Stats() Stats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return Stats{
+		Submitted: s.submitted,
+		Included:  s.included,
+		Pending:   uint64(len(s.pending)),
+		Dropped:   s.dropped,
+		Failed:    s.failed,
+	}
+}
+
+func (s *Spammer) report(ctx context.Context) {
+	ticker := time.NewTicker(s.config.ReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stats := s.Stats()
+		s.log.WithFields(logrus.Fields{
+			"submitted": stats.Submitted,
+			"included":  stats.Included,
+			"pending":   stats.Pending,
+			"dropped":   stats.Dropped,
+			"failed":    stats.Failed,
+		}).Info("Spammer report")
+	}
+}
@@ -0,0 +1,159 @@
+package txspammer
+
+import (
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// testKeys returns n private keys
+func testKeys(n int) []string {
+	keys := []string{}
+	for i := 1; i <= n; i++ {
+		keys = append(keys, hexutil.Encode(common.LeftPadBytes(big.NewInt(int64(i)).Bytes(), 32)))
+	}
+	return keys
+}
+
+func newTestSpammer(t *testing.T, keys int, txTypes ...string) *Spammer {
+	config := DefaultConfig()
+	config.LogOutput = io.Discard
+	config.PrivateKeys = testKeys(keys)
+	config.TxTypes = txTypes
+
+	s, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create the spammer: %v", err)
+	}
+	return s
+}
+
+func TestNewAccounts(t *testing.T) {
+	cases := []struct {
+		name         string
+		keys         int
+		txTypes      []string
+		accounts     int
+		blobAccounts int
+	}{
+		{"no blob", 4, []string{TxTypeDynamic, TxTypeLegacy}, 4, 4},
+		{"only blob", 4, []string{TxTypeBlob}, 4, 4},
+		{"blob and dynamic", 4, []string{TxTypeBlob, TxTypeDynamic}, 2, 2},
+		{"blob and dynamic odd", 5, []string{TxTypeDynamic, TxTypeBlob}, 2, 3},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newTestSpammer(t, c.keys, c.txTypes...)
+			if len(s.accounts) != c.accounts || len(s.blobAccounts) != c.blobAccounts {
+				t.Fatalf("expected %d accounts and %d blob accounts, got %d and %d", c.accounts, c.blobAccounts, len(s.accounts), len(s.blobAccounts))
+			}
+			if len(s.allAccounts) != c.keys {
+				t.Fatalf("expected %d accounts to sync, got %d", c.keys, len(s.allAccounts))
+			}
+
+			// the blob and non-blob transactions are not sent from the same account
+			if c.accounts+c.blobAccounts == c.keys {
+				for _, acct := range s.accounts {
+					for _, blobAcct := range s.blobAccounts {
+						if acct == blobAcct {
+							t.Fatalf("account %s sends blob and non-blob transactions", acct.addr)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	cases := []struct {
+		name   string
+		config func(*Config)
+	}{
+		{"no tps", func(c *Config) { c.TPS = 0 }},
+		{"no keys", func(c *Config) { c.PrivateKeys = nil }},
+		{"no tx types", func(c *Config) { c.TxTypes = nil }},
+		{"invalid tx type", func(c *Config) { c.TxTypes = []string{"eip7702"} }},
+		{"invalid key", func(c *Config) { c.PrivateKeys = []string{"0xzz"} }},
+		{"blob with one key", func(c *Config) {
+			c.PrivateKeys = testKeys(1)
+			c.TxTypes = []string{TxTypeBlob, TxTypeDynamic}
+		}},
+		{"no bundle size", func(c *Config) {
+			c.BundleRPCURL = "http://localhost:8645"
+			c.BundleSize = 0
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.LogOutput = io.Discard
+			config.PrivateKeys = testKeys(2)
+			c.config(config)
+			if _, err := New(config); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestNextAccount(t *testing.T) {
+	s := newTestSpammer(t, 4, TxTypeDynamic, TxTypeBlob)
+	accounts, blobAccounts := s.accounts, s.blobAccounts
+
+	// each tx type goes through its own accounts in round robin
+	cases := []struct {
+		txType   string
+		expected *account
+	}{
+		{TxTypeDynamic, accounts[0]},
+		{TxTypeBlob, blobAccounts[0]},
+		{TxTypeDynamic, accounts[1]},
+		{TxTypeLegacy, accounts[0]},
+		{TxTypeDynamic, accounts[0]},
+		{TxTypeBlob, blobAccounts[1]},
+		{TxTypeBlob, blobAccounts[0]},
+	}
+	for i, c := range cases {
+		if acct := s.nextAccount(c.txType); acct != c.expected {
+			t.Fatalf("unexpected account for the tx %d (%s): %s", i, c.txType, acct.addr)
+		}
+	}
+}
+
+func TestRecordBlock(t *testing.T) {
+	newTx := func(nonce uint64) *types.Transaction {
+		return types.NewTx(&types.LegacyTx{Nonce: nonce, To: &common.Address{}, Gas: 21000, GasPrice: big.NewInt(1)})
+	}
+	newBlock := func(num uint64, txs ...*types.Transaction) *types.Block {
+		return types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(num)}).WithBody(types.Body{Transactions: txs})
+	}
+	tx1, tx2, tx3 := newTx(1), newTx(2), newTx(3)
+
+	cases := []struct {
+		name     string
+		block    *types.Block
+		expected Stats
+	}{
+		{"included", newBlock(11, tx1), Stats{Submitted: 3, Included: 1, Pending: 2}},
+		{"not included yet", newBlock(10+pendingTimeoutBlocks, tx1), Stats{Submitted: 3, Included: 1, Pending: 2}},
+		{"dropped after the timeout", newBlock(11+pendingTimeoutBlocks, tx3), Stats{Submitted: 3, Included: 2, Dropped: 1}},
+		{"dropped only once", newBlock(12+pendingTimeoutBlocks, tx2), Stats{Submitted: 3, Included: 2, Dropped: 1}},
+	}
+
+	s := newTestSpammer(t, 1, TxTypeDynamic)
+	s.blockNumber = 10
+	for _, tx := range []*types.Transaction{tx1, tx2, tx3} {
+		s.recordSubmitted(tx)
+	}
+	for _, c := range cases {
+		s.recordBlock(c.block)
+		if stats := s.Stats(); stats != c.expected {
+			t.Fatalf("%s: expected %+v, got %+v", c.name, c.expected, stats)
+		}
+	}
+}